@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/selectel/external-dns-selectel-webhook/internal/selprovider"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	preflight  bool
+	checkWrite bool
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "check the credentials and permissions",
+	Long: "check the credentials with Keystone, list the managed zones and verify that every entry of the domain " +
+		"filter matches one of them. With --check-write a canary TXT record set is created and deleted to verify " +
+		"the write permission. The command fails if a step failed.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, _, err := getLogger("stderr")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
+
+		selProvider, err := newProvider(logger)
+		if err != nil {
+			return err
+		}
+
+		report := selProvider.Check(cmd.Context(), checkWrite)
+		if err := writeCheckReport(cmd.OutOrStdout(), report); err != nil {
+			return err
+		}
+
+		if !report.Passed() {
+			return errors.New("check failed")
+		}
+
+		return nil
+	},
+}
+
+// writeCheckReport writes a line with the status, step and message of every step of the report.
+func writeCheckReport(w io.Writer, report *selprovider.CheckReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, result := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(result.Status), result.Step, result.Message)
+	}
+
+	return tw.Flush()
+}
+
+// logCheckReport logs the steps of the report of the preflight check.
+func logCheckReport(logger *zap.Logger, report *selprovider.CheckReport) {
+	for _, result := range report.Results {
+		fields := []zap.Field{zap.String("step", result.Step), zap.String("status", result.Status)}
+		switch result.Status {
+		case selprovider.CheckPassed:
+			logger.Info(result.Message, fields...)
+		case selprovider.CheckSkipped:
+			logger.Warn(result.Message, fields...)
+		default:
+			logger.Error(result.Message, fields...)
+		}
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
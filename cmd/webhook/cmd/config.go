@@ -0,0 +1,354 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// minZoneTTL and maxZoneTTL are the TTL limits of the Selectel DNS API.
+	minZoneTTL = 60
+	maxZoneTTL = 604800
+)
+
+// credentialFlags must not be set in the config file, which is usually not kept secret. They are set by environment
+// variables or referenced by --password-file instead.
+var credentialFlags = []string{"password", "log-level-token"}
+
+// reloadableFlags are applied on SIGHUP. Changes of the other flags in the config file take effect after a restart.
+var reloadableFlags = []string{
+	"domain-filter",
+	"exclude-domains",
+	"regex-domain-filter",
+	"regex-domain-exclusion",
+	"zone-id-filter",
+	"skip-disabled-zones",
+	"skip-undelegated-zones",
+	"zone-ttl",
+	"worker",
+	"records-cache-ttl",
+	"full-resync-interval",
+	"zone-records-max-age",
+	"dry-run",
+	"log-level",
+	"ownership-allowed-names",
+	"ownership-require-txt",
+	"ownership-owner-id",
+	"ownership-txt-prefix",
+	"max-deletions",
+	"max-deletion-percent",
+	"mass-deletion-override",
+	"rate-limit",
+	"rate-limit-burst",
+}
+
+var (
+	// pinnedFlags are set on the command line or by environment variables and take precedence over the config file.
+	pinnedFlags map[string]bool
+	// fileFlags are set by the config file, they are reset to their defaults if they are removed from it.
+	fileFlags map[string]bool
+	// flagDefaults are the default values of all flags.
+	flagDefaults map[string][]string
+)
+
+// initConfig sets the flags that are not set on the command line from the environment variables and the config
+// file and validates them.
+func initConfig(flags *pflag.FlagSet) error {
+	flagDefaults = getFlagValues(flags)
+
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	viper.AutomaticEnv()
+
+	// There is some issue, where the integration of Cobra with Viper will result in wrong values, therefore we are
+	// setting the values from viper manually. The issue is, that with the standard integration, viper will see, that
+	// Cobra parameters are set - even if the command line parameter was not used and the default value was set. But
+	// when Viper notices that the value is set, it will not overwrite the default value with the environment variable.
+	// Another possibility would be to not have any default values set for cobra command line parameters, but this would
+	// break the automatic help output from the cli. The manual way here seems the best solution for now.
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err == nil && !f.Changed && viper.IsSet(f.Name) {
+			if setErr := flags.Set(f.Name, fmt.Sprint(viper.Get(f.Name))); setErr != nil {
+				err = fmt.Errorf("invalid value of the environment variable of --%s: %w", f.Name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	pinnedFlags = make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) {
+		pinnedFlags[f.Name] = true
+	})
+
+	if configFile != "" {
+		if _, err := applyConfigFile(flags, false); err != nil {
+			return err
+		}
+	} else if err := validateFlags(); err != nil {
+		return err
+	}
+
+	return readPasswordFile()
+}
+
+// applyConfigFile sets the flags that are not pinned from the config file. On reload only the reloadable flags are
+// set and the names of the other changed flags are returned. The flags are left unchanged if the config file is
+// invalid.
+func applyConfigFile(flags *pflag.FlagSet, reload bool) ([]string, error) {
+	v := viper.NewWithOptions(viper.KeyDelimiter("::"))
+	v.SetConfigFile(configFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configFile, err)
+	}
+
+	settings := v.AllSettings()
+	values := make(map[string][]string, len(settings))
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(settings)) {
+		switch {
+		case flags.Lookup(name) == nil || name == "config":
+			errs = append(errs, fmt.Errorf("unknown setting %q", name))
+
+			continue
+		case slices.Contains(credentialFlags, name):
+			errs = append(errs, fmt.Errorf(
+				"%q must not be set in the config file, use the %s environment variable instead",
+				name, strings.ToUpper(strings.ReplaceAll(name, "-", "_")),
+			))
+
+			continue
+		case pinnedFlags[name]:
+			continue
+		}
+
+		value, err := getConfigValue(settings[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value of %q: %w", name, err))
+
+			continue
+		}
+		values[name] = value
+	}
+
+	// removed settings are reset to their defaults
+	for name := range fileFlags {
+		if _, ok := values[name]; !ok {
+			values[name] = flagDefaults[name]
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, errors.Join(errs...))
+	}
+
+	previous := getFlagValues(flags)
+
+	var restart []string
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if reload && !slices.Contains(reloadableFlags, name) {
+			if !slices.Equal(previous[name], values[name]) {
+				restart = append(restart, name)
+			}
+
+			continue
+		}
+
+		if err := setFlagValue(flags.Lookup(name), values[name]); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value of %q: %w", name, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		restoreFlags(flags, previous)
+
+		return nil, fmt.Errorf("invalid config file %s: %w", configFile, err)
+	}
+
+	if err := validateFlags(); err != nil {
+		restoreFlags(flags, previous)
+
+		return nil, err
+	}
+
+	fileFlags = make(map[string]bool, len(settings))
+	for name := range settings {
+		if !pinnedFlags[name] {
+			fileFlags[name] = true
+		}
+	}
+
+	return restart, nil
+}
+
+// getConfigValue returns the flag values of a setting of the config file. Lists are set as repeated values and
+// maps as key=value pairs.
+func getConfigValue(setting any) ([]string, error) {
+	switch value := setting.(type) {
+	case nil:
+		return nil, errors.New("no value")
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			values = append(values, fmt.Sprint(item))
+		}
+
+		return values, nil
+	case map[string]any:
+		values := make([]string, 0, len(value))
+		for _, key := range slices.Sorted(maps.Keys(value)) {
+			values = append(values, fmt.Sprintf("%s=%v", key, value[key]))
+		}
+
+		return values, nil
+	default:
+		return []string{fmt.Sprint(value)}, nil
+	}
+}
+
+// getFlagValues returns the current values of all flags.
+func getFlagValues(flags *pflag.FlagSet) map[string][]string {
+	values := make(map[string][]string)
+	flags.VisitAll(func(f *pflag.Flag) {
+		if sliceValue, ok := f.Value.(pflag.SliceValue); ok {
+			values[f.Name] = sliceValue.GetSlice()
+		} else {
+			values[f.Name] = []string{f.Value.String()}
+		}
+	})
+
+	return values
+}
+
+func setFlagValue(flag *pflag.Flag, values []string) error {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		return sliceValue.Replace(values)
+	}
+
+	if len(values) != 1 {
+		return fmt.Errorf("expected a single value, got %d", len(values))
+	}
+
+	return flag.Value.Set(values[0])
+}
+
+func restoreFlags(flags *pflag.FlagSet, values map[string][]string) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		// the values were read from the flags, so they are valid
+		_ = setFlagValue(f, values[f.Name])
+	})
+}
+
+// readPasswordFile sets the password from --password-file.
+func readPasswordFile() error {
+	if passwordFile == "" {
+		return nil
+	}
+
+	if password != "" {
+		return errors.New("--password and --password-file can not be combined")
+	}
+
+	content, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return fmt.Errorf("failed to read password file: %w", err)
+	}
+	password = strings.TrimSpace(string(content))
+
+	return nil
+}
+
+// validateFlags returns all invalid flag values at once.
+func validateFlags() error {
+	var errs []error
+
+	if _, err := logging.ParseLevel(logLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if !slices.Contains(logging.Formats, logFormat) {
+		errs = append(errs, fmt.Errorf(
+			"unknown log format %q, possible values are: %s", logFormat, strings.Join(logging.Formats, ", "),
+		))
+	}
+	if worker < 1 {
+		errs = append(errs, fmt.Errorf("--worker must be at least 1, got %d", worker))
+	}
+	if recordsCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("--records-cache-ttl must not be negative, got %s", recordsCacheTTL))
+	}
+	if fullResyncInterval < 0 {
+		errs = append(errs, fmt.Errorf("--full-resync-interval must not be negative, got %s", fullResyncInterval))
+	}
+	if zoneRecordsMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("--zone-records-max-age must not be negative, got %s", zoneRecordsMaxAge))
+	}
+	if requestTimeout < 0 {
+		errs = append(errs, fmt.Errorf("--request-timeout must not be negative, got %s", requestTimeout))
+	}
+	if readinessDelay < 0 {
+		errs = append(errs, fmt.Errorf("--shutdown-readiness-delay must not be negative, got %s", readinessDelay))
+	}
+	if shutdownGracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("--shutdown-grace-period must not be negative, got %s", shutdownGracePeriod))
+	}
+	if maxDeletions < 0 {
+		errs = append(errs, fmt.Errorf("--max-deletions must not be negative, got %d", maxDeletions))
+	}
+	if maxDeletionPercent < 0 || maxDeletionPercent > 100 {
+		errs = append(errs, fmt.Errorf("--max-deletion-percent must be between 0 and 100, got %v", maxDeletionPercent))
+	}
+	if auditLogMaxSize > 0 && auditLogMaxBackups < 1 {
+		errs = append(errs, fmt.Errorf(
+			"--audit-log-max-backups must be at least 1 if --audit-log-max-size is set, got %d", auditLogMaxBackups,
+		))
+	}
+	if rateLimit < 0 {
+		errs = append(errs, fmt.Errorf("--rate-limit must not be negative, got %v", rateLimit))
+	}
+	if rateLimit > 0 && rateLimitBurst < 1 {
+		errs = append(errs, fmt.Errorf("--rate-limit-burst must be at least 1, got %d", rateLimitBurst))
+	}
+	if _, err := getZoneTTLs(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := getDomainFilter(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := getOwnershipGuardConfig(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// getZoneTTLs returns the TTLs by zone name configured by --zone-ttl.
+func getZoneTTLs() (map[string]int, error) {
+	zoneTTLs := make(map[string]int, len(zoneTTL))
+	for _, value := range zoneTTL {
+		zone, ttlValue, found := strings.Cut(value, "=")
+		if !found || zone == "" {
+			return nil, fmt.Errorf("invalid zone TTL %q, expected zone=ttl", value)
+		}
+
+		ttl, err := strconv.Atoi(ttlValue)
+		if err != nil || ttl < minZoneTTL || ttl > maxZoneTTL {
+			return nil, fmt.Errorf(
+				"invalid TTL of zone %s %q, expected seconds between %d and %d", zone, ttlValue, minZoneTTL, maxZoneTTL,
+			)
+		}
+		zoneTTLs[zone] = ttl
+	}
+
+	return zoneTTLs, nil
+}
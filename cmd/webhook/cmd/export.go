@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/selectel/external-dns-selectel-webhook/internal/selprovider"
+	"github.com/spf13/cobra"
+)
+
+const (
+	exportFormatZone = "zone"
+	exportFormatJSON = "json"
+)
+
+var (
+	exportFormat string
+	exportOutput string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export the managed zones",
+	Long: "export all record sets of the managed zones in the RFC 1035 zone file or JSON format. " +
+		"The zones are selected by --domain-filter.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportFormat != exportFormatZone && exportFormat != exportFormatJSON {
+			return fmt.Errorf("unsupported export format %q", exportFormat)
+		}
+
+		logger, _, err := getLogger("stderr")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
+
+		selProvider, err := newProvider(logger)
+		if err != nil {
+			return err
+		}
+
+		zones, err := selProvider.Export(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		if exportOutput == "" {
+			return writeExport(cmd.OutOrStdout(), zones)
+		}
+
+		file, err := os.Create(exportOutput)
+		if err != nil {
+			return err
+		}
+
+		// the end of the export may only be written on close, so a failed close fails the export
+		err = writeExport(file, zones)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+
+		return err
+	},
+}
+
+// writeExport writes the zones in the export format.
+func writeExport(w io.Writer, zones []*selprovider.ZoneExport) error {
+	if exportFormat == exportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(zones)
+	}
+
+	return selprovider.WriteZoneFile(w, zones)
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", exportFormatZone, "Specifies the export format. "+
+		"Possible values are: zone, json.")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Specifies the file to write the export to. "+
+		"The export is written to stdout if empty.")
+
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+const fakeServerReadHeaderTimeout = 10 * time.Second
+
+var (
+	fakeServerAddress        string
+	fakeServerZones          []string
+	fakeServerRateLimitEvery int
+)
+
+var fakeServerCmd = &cobra.Command{
+	Use:   "fake-server",
+	Short: "run an in-memory fake of the Selectel DNS API",
+	Long: "run an in-memory fake of the Selectel DNS API v2 and the Keystone token endpoint for integration " +
+		"tests and local setups. Tokens are issued for --username and --password, any credentials are accepted " +
+		"if they are empty. All state is lost on exit.",
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, _, err := getLogger("stdout")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
+
+		fake := fakeselectel.New(fakeselectel.Config{
+			Username:       username,
+			Password:       password,
+			RateLimitEvery: fakeServerRateLimitEvery,
+		})
+
+		for _, name := range fakeServerZones {
+			zone, err := fake.AddZone(name)
+			if err != nil {
+				return err
+			}
+			logger.Info("zone created", zap.String("name", zone.Name), zap.String("id", zone.ID))
+		}
+
+		logger.Info(
+			"starting fake server",
+			zap.String("address", fakeServerAddress),
+			zap.String("authURL", "http://"+fakeServerAddress+fakeselectel.KeystonePath),
+			zap.String("baseURL", "http://"+fakeServerAddress+fakeselectel.DomainsPath),
+		)
+
+		server := &http.Server{
+			Addr:              fakeServerAddress,
+			Handler:           fake,
+			ReadHeaderTimeout: fakeServerReadHeaderTimeout,
+		}
+
+		return server.ListenAndServe()
+	},
+}
+
+func init() {
+	fakeServerCmd.Flags().StringVar(&fakeServerAddress, "address", "127.0.0.1:8889", "Specifies the address "+
+		"to listen on.")
+	fakeServerCmd.Flags().StringArrayVar(&fakeServerZones, "zone", []string{}, "Establishes the zones that "+
+		"exist on start.")
+	fakeServerCmd.Flags().IntVar(&fakeServerRateLimitEvery, "rate-limit-every", 0, "Specifies that every n-th "+
+		"request to the DNS API is answered with 429 Too Many Requests. 0 disables it.")
+
+	rootCmd.AddCommand(fakeServerCmd)
+}
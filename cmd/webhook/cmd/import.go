@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/selectel/external-dns-selectel-webhook/internal/selprovider"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var importOrigin string
+
+var importCmd = &cobra.Command{
+	Use:   "import <zone-file>",
+	Short: "import a zone file into the managed zones",
+	Long: "import a RFC 1035 zone file into the managed zones. Missing record sets are created and record sets " +
+		"with different content or TTL are updated. Record sets missing in the zone file are kept. " +
+		"With --dry-run the changes are printed as JSON instead of being applied.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		endpoints, err := selprovider.ParseZoneFile(file, importOrigin)
+		if err != nil {
+			return err
+		}
+
+		logger, _, err := getLogger("stderr")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
+
+		selProvider, err := newProvider(logger)
+		if err != nil {
+			return err
+		}
+
+		changes, err := selProvider.Import(cmd.Context(), endpoints)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+
+			report := selProvider.DryRunReport()
+			if report == nil {
+				report = &selprovider.DryRunReport{}
+			}
+
+			return encoder.Encode(report)
+		}
+
+		logger.Info(
+			"zone file imported",
+			zap.Int("created", len(changes.Create)),
+			zap.Int("updated", len(changes.UpdateNew)),
+		)
+
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importOrigin, "origin", "", "Specifies the origin relative names are resolved "+
+		"against if the zone file does not set $ORIGIN.")
+
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const (
+	planFormatText = "text"
+	planFormatJSON = "json"
+)
+
+var (
+	planFormat             string
+	planPolicy             string
+	planApply              bool
+	planManagedRecordTypes []string
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan <endpoints-file>",
+	Short: "diff desired endpoints against the live records",
+	Long: "plan reads a JSON file with the desired endpoints in the external-dns format and computes the records " +
+		"to create, update and delete the same way external-dns does. With --apply the changes are applied " +
+		"afterwards, which respects --dry-run and the safety guards of the webhook.",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if planFormat != planFormatText && planFormat != planFormatJSON {
+			return fmt.Errorf("unsupported plan format %q", planFormat)
+		}
+
+		policy, ok := plan.Policies[planPolicy]
+		if !ok {
+			return fmt.Errorf("unsupported plan policy %q", planPolicy)
+		}
+
+		desired, err := readEndpoints(args[0])
+		if err != nil {
+			return err
+		}
+
+		logger, _, err := getLogger("stderr")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
+
+		selProvider, err := newProvider(logger)
+		if err != nil {
+			return err
+		}
+
+		current, err := selProvider.Records(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		desired, err = selProvider.AdjustEndpoints(desired)
+		if err != nil {
+			return err
+		}
+
+		calculated := (&plan.Plan{
+			Current:        current,
+			Desired:        desired,
+			Policies:       []plan.Policy{policy},
+			DomainFilter:   endpoint.MatchAllDomainFilters{selProvider.GetDomainFilter()},
+			ManagedRecords: planManagedRecordTypes,
+		}).Calculate()
+
+		if planFormat == planFormatJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			err = encoder.Encode(calculated.Changes)
+		} else {
+			err = writePlan(cmd.OutOrStdout(), calculated.Changes)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !planApply || !calculated.Changes.HasChanges() {
+			return nil
+		}
+
+		err = selProvider.ApplyChanges(cmd.Context(), calculated.Changes)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("plan applied", zap.Bool("dryRun", dryRun))
+
+		return nil
+	},
+}
+
+// readEndpoints reads a JSON array of endpoints from the given file.
+func readEndpoints(path string) ([]*endpoint.Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*endpoint.Endpoint
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse endpoints file: %w", err)
+	}
+
+	return endpoints, nil
+}
+
+// writePlan writes a human-readable diff of the changes.
+func writePlan(w io.Writer, changes *plan.Changes) error {
+	lines := make([]string, 0, len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete)+1)
+
+	for _, ep := range changes.Create {
+		lines = append(lines, "+ "+formatPlanEndpoint(ep))
+	}
+	for i, ep := range changes.UpdateNew {
+		line := "~ " + formatPlanEndpoint(ep)
+		if i < len(changes.UpdateOld) {
+			old := changes.UpdateOld[i]
+			line += fmt.Sprintf(" (was %d %s)", old.RecordTTL, strings.Join(old.Targets, ","))
+		}
+		lines = append(lines, line)
+	}
+	for _, ep := range changes.Delete {
+		lines = append(lines, "- "+formatPlanEndpoint(ep))
+	}
+
+	lines = append(lines, fmt.Sprintf(
+		"Plan: %d to create, %d to update, %d to delete.",
+		len(changes.Create), len(changes.UpdateNew), len(changes.Delete),
+	))
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+
+	return err
+}
+
+func formatPlanEndpoint(ep *endpoint.Endpoint) string {
+	return fmt.Sprintf("%s %s %d %s", ep.DNSName, ep.RecordType, ep.RecordTTL, strings.Join(ep.Targets, ","))
+}
+
+func init() {
+	planCmd.Flags().StringVar(&planFormat, "format", planFormatText, "Specifies the output format. "+
+		"Possible values are: text, json.")
+	// without the TXT registry of external-dns the plan does not know which records are owned, so only sync
+	// deletes records missing from the endpoints file
+	planCmd.Flags().StringVar(&planPolicy, "policy", "upsert-only", "Specifies the policy the changes are "+
+		"calculated with like the external-dns flag of the same name. Possible values are: upsert-only, "+
+		"create-only, sync. With sync every live record missing from the endpoints file is deleted, whether "+
+		"external-dns owns it or not.")
+	planCmd.Flags().BoolVar(&planApply, "apply", false, "Specifies whether the changes are applied after "+
+		"printing them.")
+	planCmd.Flags().StringSliceVar(&planManagedRecordTypes, "managed-record-types", []string{
+		endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME,
+	}, "Specifies the record types considered for management like the external-dns flag of the same name.")
+
+	rootCmd.AddCommand(planCmd)
+}
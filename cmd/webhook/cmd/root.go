@@ -1,34 +1,90 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/selectel/external-dns-selectel-webhook/internal/selprovider"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/httpdefault"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/keystone"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/time/rate"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
 var (
-	authorizationURL string
-	accountID        string
-	username         string
-	password         string
-	projectID        string
-	apiPort          string
-	baseURL          string
-	worker           int
-	domainFilter     []string
-	dryRun           bool
-	logLevel         string
+	authorizationURL     string
+	accountID            string
+	username             string
+	password             string
+	projectID            string
+	apiPort              string
+	requestTimeout       time.Duration
+	shutdownGracePeriod  time.Duration
+	readinessDelay       time.Duration
+	baseURL              string
+	worker               int
+	recordsCacheTTL      time.Duration
+	fullResyncInterval   time.Duration
+	zoneRecordsMaxAge    time.Duration
+	domainFilter         []string
+	excludeDomains       []string
+	regexDomainFilter    string
+	regexDomainExclusion string
+	zoneIDFilter         []string
+	skipDisabledZones    bool
+	skipUndelegatedZones bool
+	dryRun               bool
+	logLevel             string
+
+	logFormat             string
+	logSamplingInitial    int
+	logSamplingThereafter int
+	logLevelToken         string
+	logLevelRevertAfter   time.Duration
+
+	ownershipAllowedNames []string
+	ownershipRequireTXT   bool
+	ownershipOwnerID      string
+	ownershipTXTPrefix    string
+
+	maxDeletions         int
+	maxDeletionPercent   float64
+	massDeletionOverride bool
+
+	auditLog           string
+	auditLogMaxSize    int
+	auditLogMaxBackups int
+
+	httpTimeout             time.Duration
+	httpDialTimeout         time.Duration
+	httpMaxIdleConns        int
+	httpMaxIdleConnsPerHost int
+	httpMaxConnsPerHost     int
+	httpEnableHTTP2         bool
+	caFile                  string
+	httpsProxy              string
+	noProxy                 string
+	insecureSkipVerify      bool
+
+	configFile     string
+	passwordFile   string
+	zoneTTL        []string
+	rateLimit      float64
+	rateLimitBurst int
+
+	// rateLimiter limits the requests to the Keystone and DNS API, it is reconfigured on reload.
+	rateLimiter = rate.NewLimiter(rate.Inf, 0)
 )
 
 const (
@@ -42,74 +98,281 @@ var rootCmd = &cobra.Command{
 	Use:   "external-dns-selectel-webhook",
 	Short: "provider webhook for the Selectel DNS service",
 	Long:  "provider webhook for the Selectel DNS service",
-	Run: func(cmd *cobra.Command, args []string) {
-		logger := getLogger()
-		defer func(logger *zap.Logger) {
-			err := logger.Sync()
-			if err != nil {
-				log.Printf("synchronization of logs failed with error: %v", err)
-			}
-		}(logger)
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// the flags are parsed at this point, so the usage does not help with errors of the config
+		cmd.SilenceUsage = true
 
-		endpointDomainFilter := endpoint.DomainFilter{Filters: domainFilter}
+		return initConfig(cmd.Root().PersistentFlags())
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger, levels, err := getLogger("stdout")
+		if err != nil {
+			return err
+		}
+		defer syncLogger(logger)
 
-		keystoneProvider := keystone.NewProvider(logger, keystone.Credentials{
-			IdentityEndpoint: authorizationURL,
-			AccountID:        accountID,
-			ProjectID:        projectID,
-			Username:         username,
-			Password:         password,
-		})
+		notifyLogLevelToggle(logger, levels, logLevelRevertAfter)
 
-		selProvider, err := selprovider.New(selprovider.Config{
-			BaseURL:          baseURL,
-			KeystoneProvider: keystoneProvider,
-			DomainFilter:     endpointDomainFilter,
-			DryRun:           dryRun,
-			Workers:          worker,
-		}, logger.With(zap.String("component", "selprovider")))
+		selProvider, err := newProvider(logger)
 		if err != nil {
-			panic(err)
+			return err
 		}
 
-		app := api.New(logger.With(zap.String("component", "api")), metrics.NewHttpApiMetrics(), selProvider)
-		err = app.Listen(apiPort)
-		if err != nil {
-			panic(err)
+		if preflight {
+			report := selProvider.Check(cmd.Context(), checkWrite)
+			logCheckReport(logger.With(zap.String(logging.ComponentKey, "preflight")), report)
+			if !report.Passed() {
+				return errors.New("preflight check failed")
+			}
 		}
+
+		notifyReload(logger, func() error {
+			return reloadConfig(cmd.PersistentFlags(), logger, selProvider, levels)
+		})
+
+		app := api.New(
+			logger.With(zap.String(logging.ComponentKey, "api")),
+			metrics.NewHttpApiMetrics(),
+			selProvider,
+			api.WithRequestTimeout(requestTimeout),
+			api.WithReadinessDelay(readinessDelay),
+			api.WithShutdownGracePeriod(shutdownGracePeriod),
+			api.WithLogLevels(levels, logLevelToken, logLevelRevertAfter),
+		)
+
+		return app.Listen(apiPort)
 	},
 }
 
-func getLogger() *zap.Logger {
-	cfg := zap.Config{
-		Level:    zap.NewAtomicLevelAt(getZapLogLevel()),
-		Encoding: "json", // or "console"
-		// ... other zap configuration as needed
-		EncoderConfig:    zap.NewProductionEncoderConfig(),
-		OutputPaths:      []string{"stdout"},
-		ErrorOutputPaths: []string{"stderr"},
+// newProvider returns the Selectel provider configured by the command line flags.
+func newProvider(logger *zap.Logger) (*selprovider.Provider, error) {
+	// the client is shared by the Keystone and domains clients to reuse the connections
+	httpClient, err := httpdefault.NewClient(httpdefault.Options{
+		Timeout:             httpTimeout,
+		DialTimeout:         httpDialTimeout,
+		MaxIdleConns:        httpMaxIdleConns,
+		MaxIdleConnsPerHost: httpMaxIdleConnsPerHost,
+		MaxConnsPerHost:     httpMaxConnsPerHost,
+		EnableHTTP2:         httpEnableHTTP2,
+		CAFile:              caFile,
+		CAReloadInterval:    httpdefault.DefaultOptions().CAReloadInterval,
+		OnCAReload: func(err error) {
+			if err != nil {
+				logger.Error("failed to reload CA file, keeping the previous one", zap.String("ca_file", caFile), zap.Error(err))
+
+				return
+			}
+			logger.Info("CA file reloaded", zap.String("ca_file", caFile))
+		},
+		HTTPSProxy:         httpsProxy,
+		NoProxy:            noProxy,
+		InsecureSkipVerify: insecureSkipVerify,
+		RateLimiter:        rateLimiter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keystoneLogger := logger.With(zap.String(logging.ComponentKey, "keystone"))
+	keystoneProvider := keystone.NewProvider(keystoneLogger, keystone.Credentials{
+		IdentityEndpoint: authorizationURL,
+		AccountID:        accountID,
+		ProjectID:        projectID,
+		Username:         username,
+		Password:         password,
+	}, httpClient)
+
+	auditSink, err := getAuditSink()
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getProviderConfig()
+	if err != nil {
+		return nil, err
+	}
+	config.BaseURL = baseURL
+	config.HTTPClient = httpClient
+	config.KeystoneProvider = keystoneProvider
+	config.AuditSink = auditSink
+	config.Metrics = metrics.NewProviderMetrics()
+
+	return selprovider.New(config, logger.With(zap.String(logging.ComponentKey, "selprovider")))
+}
+
+// getProviderConfig returns the reloadable part of the provider config and configures the rate limiter.
+func getProviderConfig() (selprovider.Config, error) {
+	endpointDomainFilter, err := getDomainFilter()
+	if err != nil {
+		return selprovider.Config{}, err
 	}
 
-	logger, errLogger := cfg.Build()
-	if errLogger != nil {
-		panic(errLogger)
+	ownershipGuard, err := getOwnershipGuardConfig()
+	if err != nil {
+		return selprovider.Config{}, err
 	}
 
-	return logger
+	zoneTTLs, err := getZoneTTLs()
+	if err != nil {
+		return selprovider.Config{}, err
+	}
+
+	if rateLimit > 0 {
+		rateLimiter.SetLimit(rate.Limit(rateLimit))
+		rateLimiter.SetBurst(rateLimitBurst)
+	} else {
+		rateLimiter.SetLimit(rate.Inf)
+	}
+
+	return selprovider.Config{
+		DomainFilter: endpointDomainFilter,
+		ZoneFilter: selprovider.ZoneFilterConfig{
+			ZoneIDs:         zoneIDFilter,
+			SkipDisabled:    skipDisabledZones,
+			SkipUndelegated: skipUndelegatedZones,
+		},
+		ZoneTTLs:           zoneTTLs,
+		DryRun:             dryRun,
+		Workers:            worker,
+		RecordsCacheTTL:    recordsCacheTTL,
+		FullResyncInterval: fullResyncInterval,
+		ZoneRecordsMaxAge:  zoneRecordsMaxAge,
+		OwnershipGuard:     ownershipGuard,
+		MassDeletionGuard: selprovider.MassDeletionGuardConfig{
+			MaxDeletions:       maxDeletions,
+			MaxDeletionPercent: maxDeletionPercent,
+			Override:           massDeletionOverride,
+		},
+	}, nil
 }
 
-func getZapLogLevel() zapcore.Level {
-	switch logLevel {
-	case "debug":
-		return zapcore.DebugLevel
-	case "info":
-		return zapcore.InfoLevel
-	case "warn":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
+// reloadConfig applies the reloadable settings of the config file to the running webhook.
+func reloadConfig(
+	flags *pflag.FlagSet,
+	logger *zap.Logger,
+	selProvider *selprovider.Provider,
+	levels *logging.Levels,
+) error {
+	if configFile == "" {
+		logger.Info("no config file to reload")
+
+		return nil
+	}
+
+	restart, err := applyConfigFile(flags, true)
+	if err != nil {
+		return err
+	}
+
+	config, err := getProviderConfig()
+	if err != nil {
+		return err
+	}
+	selProvider.Reload(config)
+
+	// the level is validated by applyConfigFile
+	level, _ := logging.ParseLevel(logLevel)
+	levels.Configure(level)
+
+	for _, name := range restart {
+		logger.Warn("setting changed in the config file takes effect after a restart", zap.String("setting", name))
+	}
+	logger.Info("config file reloaded", zap.String("config", configFile))
+
+	return nil
+}
+
+// getDomainFilter returns the domain filter configured by the domain filter flags. Like in external-dns, the regular
+// expressions replace the lists of domains, so they can not be combined.
+func getDomainFilter() (endpoint.DomainFilter, error) {
+	if regexDomainFilter == "" && regexDomainExclusion == "" {
+		return endpoint.NewDomainFilterWithExclusions(domainFilter, excludeDomains), nil
+	}
+
+	if len(domainFilter) > 0 || len(excludeDomains) > 0 {
+		return endpoint.DomainFilter{}, errors.New(
+			"--regex-domain-filter and --regex-domain-exclusion can not be combined with --domain-filter and --exclude-domains",
+		)
+	}
+
+	var include, exclude *regexp.Regexp
+	var err error
+	if regexDomainFilter != "" {
+		include, err = regexp.Compile(regexDomainFilter)
+		if err != nil {
+			return endpoint.DomainFilter{}, fmt.Errorf("invalid regex domain filter %q: %w", regexDomainFilter, err)
+		}
+	}
+	if regexDomainExclusion != "" {
+		exclude, err = regexp.Compile(regexDomainExclusion)
+		if err != nil {
+			return endpoint.DomainFilter{}, fmt.Errorf("invalid regex domain exclusion %q: %w", regexDomainExclusion, err)
+		}
+	}
+
+	return endpoint.NewRegexDomainFilter(include, exclude), nil
+}
+
+func getOwnershipGuardConfig() (selprovider.OwnershipGuardConfig, error) {
+	allowedNames := make([]*regexp.Regexp, 0, len(ownershipAllowedNames))
+	for _, pattern := range ownershipAllowedNames {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return selprovider.OwnershipGuardConfig{}, fmt.Errorf("invalid ownership allowed name %q: %w", pattern, err)
+		}
+		allowedNames = append(allowedNames, re)
+	}
+
+	return selprovider.OwnershipGuardConfig{
+		AllowedNames:        allowedNames,
+		RequireOwnershipTXT: ownershipRequireTXT,
+		OwnerID:             ownershipOwnerID,
+		TXTPrefix:           ownershipTXTPrefix,
+	}, nil
+}
+
+// getAuditSink returns the audit sink configured by --audit-log or nil if the audit log is disabled.
+func getAuditSink() (selprovider.AuditSink, error) {
+	switch auditLog {
+	case "":
+		return nil, nil
+	case "stdout", "stderr":
+		cfg := zap.Config{
+			Level:            zap.NewAtomicLevelAt(zapcore.InfoLevel),
+			Encoding:         "json",
+			EncoderConfig:    zap.NewProductionEncoderConfig(),
+			OutputPaths:      []string{auditLog},
+			ErrorOutputPaths: []string{"stderr"},
+		}
+
+		logger, err := cfg.Build()
+		if err != nil {
+			return nil, err
+		}
+
+		return selprovider.NewLoggerAuditSink(logger.Named("audit")), nil
 	default:
-		return zapcore.InfoLevel
+		return selprovider.NewFileAuditSink(auditLog, int64(auditLogMaxSize)*1024*1024, auditLogMaxBackups)
+	}
+}
+
+// getLogger returns the logger writing to the given output path. The subcommands log to stderr
+// to keep stdout for their output.
+func getLogger(outputPath string) (*zap.Logger, *logging.Levels, error) {
+	return logging.New(logging.Config{
+		Level:              logLevel,
+		Format:             logFormat,
+		OutputPath:         outputPath,
+		SamplingInitial:    logSamplingInitial,
+		SamplingThereafter: logSamplingThereafter,
+		Secrets:            []string{password, logLevelToken},
+	})
+}
+
+func syncLogger(logger *zap.Logger) {
+	err := logger.Sync()
+	if err != nil {
+		log.Printf("synchronization of logs failed with error: %v", err)
 	}
 }
 
@@ -118,39 +381,120 @@ func Execute() error {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Specifies a YAML or JSON config file with "+
+		"the settings of the flags by their names. Flags and environment variables take precedence over it. The "+
+		"reloadable settings are applied on SIGHUP.")
 	rootCmd.PersistentFlags().StringVar(&apiPort, "api-port", "8888", "Specifies the port to listen on.")
+	rootCmd.PersistentFlags().DurationVar(&requestTimeout, "request-timeout", 0, "Specifies the deadline of "+
+		"the records and apply changes requests of external-dns including all calls to the DNS and Keystone API. "+
+		"Requests exceeding it are answered with 504 Gateway Timeout. 0 disables the deadline.")
+	rootCmd.PersistentFlags().DurationVar(&readinessDelay, "shutdown-readiness-delay", 5*time.Second,
+		"Specifies the time requests are still accepted on shutdown after the readiness probe started failing.")
+	rootCmd.PersistentFlags().DurationVar(&shutdownGracePeriod, "shutdown-grace-period", 30*time.Second,
+		"Specifies the time given to the running requests to finish on shutdown. The server waits for the first "+
+			"half of it, after the whole grace period the running changes are stopped before their next record set "+
+			"and the changes left undone are logged.")
 	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", DefaultDomainsURL, "Identifies the Base URL for utilizing the API.")
 	rootCmd.PersistentFlags().StringVar(&projectID, "project-id", "", "Specifies the project id to authorize.")
 	rootCmd.PersistentFlags().StringVar(&accountID, "account-id", "", "Specifies the account id to authorize.")
 	rootCmd.PersistentFlags().StringVar(&authorizationURL, "auth-url", DefaultAuthURL, "Identifies the URL for utilizing the API to receive keystone-token.")
 	rootCmd.PersistentFlags().StringVar(&username, "username", "", "Specifies the username of service user to authorize.")
 	rootCmd.PersistentFlags().StringVar(&password, "password", "", "Specifies the password of service user to authorize.")
+	rootCmd.PersistentFlags().StringVar(&passwordFile, "password-file", "", "Specifies a file containing the "+
+		"password of service user to authorize. Can not be combined with --password.")
 	rootCmd.PersistentFlags().IntVar(&worker, "worker", 10, "Specifies the number "+
 		"of workers to employ for querying the API. Given that we need to iterate over all zones and "+
 		"records, it can be parallelized. However, it is important to avoid setting this number "+
 		"excessively high to prevent receiving 429 rate limiting from the API.")
+	rootCmd.PersistentFlags().DurationVar(&recordsCacheTTL, "records-cache-ttl", 0, "Specifies the time the "+
+		"records read for external-dns are reused for. Concurrent reads always share a single scan of the zones. "+
+		"Applied changes drop the cached records.")
+	rootCmd.PersistentFlags().DurationVar(&fullResyncInterval, "full-resync-interval", 0, "Specifies the "+
+		"interval of full scans of the zones. In between, only the record sets of the zones modified since the last "+
+		"scan are read. 0 reads all zones on every scan.")
+	rootCmd.PersistentFlags().DurationVar(&zoneRecordsMaxAge, "zone-records-max-age", 0, "Specifies the "+
+		"maximum time the records of a zone are reused between full scans, even if the zone was not modified. 0 uses "+
+		"--full-resync-interval.")
 	rootCmd.PersistentFlags().StringArrayVar(&domainFilter, "domain-filter", []string{}, "Establishes a filter for DNS zone names.")
+	rootCmd.PersistentFlags().StringArrayVar(&excludeDomains, "exclude-domains", []string{}, "Establishes "+
+		"DNS zone names to exclude from the domain filter.")
+	rootCmd.PersistentFlags().StringVar(&regexDomainFilter, "regex-domain-filter", "", "Establishes a regular "+
+		"expression for DNS zone names to include. Replaces --domain-filter and --exclude-domains.")
+	rootCmd.PersistentFlags().StringVar(&regexDomainExclusion, "regex-domain-exclusion", "", "Establishes a "+
+		"regular expression for DNS zone names to exclude. Replaces --domain-filter and --exclude-domains.")
+	rootCmd.PersistentFlags().StringArrayVar(&zoneIDFilter, "zone-id-filter", []string{}, "Establishes the exact "+
+		"ids of the DNS zones to manage in addition to the domain filter.")
+	rootCmd.PersistentFlags().BoolVar(&skipDisabledZones, "skip-disabled-zones", false, "Specifies whether "+
+		"disabled DNS zones are skipped.")
+	rootCmd.PersistentFlags().BoolVar(&skipUndelegatedZones, "skip-undelegated-zones", false, "Specifies whether "+
+		"DNS zones whose last delegation check failed are skipped.")
+	rootCmd.PersistentFlags().StringArrayVar(&zoneTTL, "zone-ttl", []string{}, "Establishes the TTL in "+
+		"seconds of the record sets in a zone as zone=ttl, used if external-dns does not set a TTL.")
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", 0, "Specifies the maximum number of "+
+		"requests per second to the DNS and Keystone API. 0 disables the limit.")
+	rootCmd.PersistentFlags().IntVar(&rateLimitBurst, "rate-limit-burst", 10, "Specifies the number of "+
+		"requests that may exceed --rate-limit at once.")
+	rootCmd.PersistentFlags().BoolVar(&preflight, "preflight", false, "Specifies whether the webhook runs the "+
+		"check subcommand before it starts listening and exits if a step failed.")
+	rootCmd.PersistentFlags().BoolVar(&checkWrite, "check-write", false, "Specifies whether the check creates "+
+		"and deletes a canary TXT record set in the first managed zone to verify the write permission.")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Specifies whether to perform a dry run.")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Specifies the log level. Possible values are: debug, info, warn, error.")
-}
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatJSON, "Specifies the log format. "+
+		"Possible values are: "+strings.Join(logging.Formats, ", ")+".")
+	rootCmd.PersistentFlags().StringVar(&logLevelToken, "log-level-token", "", "Specifies the bearer token "+
+		"of the /debug/loglevel endpoint changing the log level at runtime and of the /debug/dry-run endpoint. "+
+		"Both endpoints are disabled if empty.")
+	rootCmd.PersistentFlags().DurationVar(&logLevelRevertAfter, "log-level-revert-after", 15*time.Minute,
+		"Specifies the duration after which a log level changed at runtime is reverted to --log-level.")
+	rootCmd.PersistentFlags().IntVar(&logSamplingInitial, "log-sampling-initial", 0, "Specifies the number of "+
+		"entries with the same message logged per second before the sampling starts. Warnings and errors are never "+
+		"sampled. 0 disables the sampling.")
+	rootCmd.PersistentFlags().IntVar(&logSamplingThereafter, "log-sampling-thereafter", 100, "Specifies that "+
+		"only every n-th entry with the same message is logged per second after --log-sampling-initial entries.")
+	rootCmd.PersistentFlags().StringArrayVar(&ownershipAllowedNames, "ownership-allowed-names", []string{}, "Establishes "+
+		"regular expressions for record names that may be updated or deleted regardless of ownership. Enables the ownership guard.")
+	rootCmd.PersistentFlags().BoolVar(&ownershipRequireTXT, "ownership-require-txt", false, "Specifies whether "+
+		"records may only be updated or deleted if they have an external-dns ownership TXT record. Enables the ownership guard.")
+	rootCmd.PersistentFlags().StringVar(&ownershipOwnerID, "ownership-owner-id", "", "Specifies the external-dns "+
+		"owner id the ownership TXT records must belong to. Any owner is accepted if empty.")
+	rootCmd.PersistentFlags().StringVar(&ownershipTXTPrefix, "ownership-txt-prefix", "", "Specifies the "+
+		"--txt-prefix external-dns is running with to find ownership TXT records.")
+	rootCmd.PersistentFlags().IntVar(&maxDeletions, "max-deletions", 0, "Specifies the maximum number of "+
+		"records a single batch of changes may delete. The whole batch is refused otherwise. 0 disables the limit.")
+	rootCmd.PersistentFlags().Float64Var(&maxDeletionPercent, "max-deletion-percent", 0, "Specifies the maximum "+
+		"share of all records in the managed zones in percent a single batch of changes may delete, including "+
+		"records not created by external-dns. The whole batch is refused otherwise. 0 disables the limit.")
+	rootCmd.PersistentFlags().BoolVar(&massDeletionOverride, "mass-deletion-override", false, "Specifies whether "+
+		"batches exceeding --max-deletions or --max-deletion-percent are applied anyway.")
+	rootCmd.PersistentFlags().StringVar(&auditLog, "audit-log", "", "Specifies where the audit events of every "+
+		"DNS mutation are written to. Possible values are: a file path, stdout, stderr. The audit log is disabled if empty.")
+	rootCmd.PersistentFlags().IntVar(&auditLogMaxSize, "audit-log-max-size", 100, "Specifies the size in "+
+		"megabytes after which the audit log file is rotated. 0 disables the rotation.")
+	rootCmd.PersistentFlags().IntVar(&auditLogMaxBackups, "audit-log-max-backups", 5, "Specifies the number "+
+		"of rotated audit log files to keep. Must be at least 1 if the rotation is enabled.")
 
-func initConfig() {
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-	viper.AutomaticEnv()
-
-	// There is some issue, where the integration of Cobra with Viper will result in wrong values, therefore we are
-	// setting the values from viper manually. The issue is, that with the standard integration, viper will see, that
-	// Cobra parameters are set - even if the command line parameter was not used and the default value was set. But
-	// when Viper notices that the value is set, it will not overwrite the default value with the environment variable.
-	// Another possibility would be to not have any default values set for cobra command line parameters, but this would
-	// break the automatic help output from the cli. The manual way here seems the best solution for now.
-	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
-		if !f.Changed && viper.IsSet(f.Name) {
-			if err := rootCmd.PersistentFlags().Set(f.Name, fmt.Sprint(viper.Get(f.Name))); err != nil {
-				log.Fatalf("unable to set value for command line parameter: %v", err)
-			}
-		}
-	})
+	defaultHTTPOptions := httpdefault.DefaultOptions()
+	rootCmd.PersistentFlags().DurationVar(&httpTimeout, "http-timeout", defaultHTTPOptions.Timeout, "Specifies "+
+		"the timeout of a single request to the DNS and Keystone API.")
+	rootCmd.PersistentFlags().DurationVar(&httpDialTimeout, "http-dial-timeout", defaultHTTPOptions.DialTimeout,
+		"Specifies the timeout of connection establishments to the DNS and Keystone API.")
+	rootCmd.PersistentFlags().IntVar(&httpMaxIdleConns, "http-max-idle-conns", defaultHTTPOptions.MaxIdleConns,
+		"Specifies the maximum number of idle (keep-alive) connections across all hosts.")
+	rootCmd.PersistentFlags().IntVar(&httpMaxIdleConnsPerHost, "http-max-idle-conns-per-host",
+		defaultHTTPOptions.MaxIdleConnsPerHost, "Specifies the maximum number of idle (keep-alive) connections per "+
+			"host. It should not be lower than --worker, otherwise the connections of the workers are not reused.")
+	rootCmd.PersistentFlags().IntVar(&httpMaxConnsPerHost, "http-max-conns-per-host", 0, "Specifies the maximum "+
+		"number of connections per host. 0 disables the limit.")
+	rootCmd.PersistentFlags().BoolVar(&httpEnableHTTP2, "http2", false, "Specifies whether HTTP/2 is used "+
+		"if the API supports it.")
+	rootCmd.PersistentFlags().StringVar(&caFile, "ca-file", "", "Specifies a PEM bundle of certificate "+
+		"authorities trusted in addition to the system ones, e.g. the one of a TLS-intercepting proxy. The file is "+
+		"reloaded when it changes.")
+	rootCmd.PersistentFlags().StringVar(&httpsProxy, "https-proxy", "", "Specifies the URL of the proxy for "+
+		"the requests to the DNS and Keystone API.")
+	rootCmd.PersistentFlags().StringVar(&noProxy, "no-proxy", "", "Specifies a comma-separated list of hosts "+
+		"that are requested without the proxy.")
+	rootCmd.PersistentFlags().BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Specifies whether "+
+		"the certificates of the DNS and Keystone API are not verified. Use it for test endpoints only.")
 }
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// notifyLogLevelToggle switches the log level between debug and --log-level on SIGUSR1. A switch to debug is
+// reverted after revertAfter.
+func notifyLogLevelToggle(logger *zap.Logger, levels *logging.Levels, revertAfter time.Duration) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1)
+
+	go func() {
+		for range sigCh {
+			level := levels.Toggle(revertAfter)
+			logger.Warn("log level toggled", zap.String("level", level.String()))
+		}
+	}()
+}
+
+// notifyReload calls reload on SIGHUP.
+func notifyReload(logger *zap.Logger, reload func() error) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	go func() {
+		for range sigCh {
+			if err := reload(); err != nil {
+				logger.Error("failed to reload the config file, keeping the previous settings", zap.Error(err))
+			}
+		}
+	}()
+}
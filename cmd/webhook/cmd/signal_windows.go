@@ -0,0 +1,16 @@
+//go:build windows
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// notifyLogLevelToggle does nothing, there is no SIGUSR1 on Windows.
+func notifyLogLevelToggle(_ *zap.Logger, _ *logging.Levels, _ time.Duration) {}
+
+// notifyReload does nothing, there is no SIGHUP on Windows.
+func notifyReload(_ *zap.Logger, _ func() error) {}
@@ -1,10 +1,14 @@
 package main
 
-import "github.com/selectel/external-dns-selectel-webhook/cmd/webhook/cmd"
+import (
+	"os"
+
+	"github.com/selectel/external-dns-selectel-webhook/cmd/webhook/cmd"
+)
 
 func main() {
-	err := cmd.Execute()
-	if err != nil {
-		panic(err)
+	// the error is printed by cobra
+	if err := cmd.Execute(); err != nil {
+		os.Exit(1)
 	}
 }
@@ -2,53 +2,167 @@ package selprovider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	domains "github.com/selectel/domains-go/pkg/v2"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/plan"
 )
 
 // ApplyChanges applies a given set of changes.
 func (p *Provider) ApplyChanges(ctx context.Context, changes *plan.Changes) error {
-	client, err := p.getDomainsClient()
+	client, err := p.getDomainsClient(ctx)
 	if err != nil {
 		return err
 	}
 
+	return p.applyChanges(ctx, client, changes)
+}
+
+// applyChanges applies a given set of changes using the given client.
+func (p *Provider) applyChanges(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	changes *plan.Changes,
+) error {
+	if !p.drainer.start() {
+		return ErrDraining
+	}
+	defer p.drainer.done()
+
+	// the changes are visible to the next Records call, even if only some were applied
+	defer p.recordsCache.invalidate()
+
+	settings := p.settings.Load()
+
+	// in dry-run mode the refusal is part of the report together with the changes the batch would have made
+	var massDeletionErr *MassDeletionError
+	err := settings.massDeletionGuard.check(ctx, changes.Delete, p.countRecords(client))
+	if err != nil && (!settings.dryRun || !errors.As(err, &massDeletionErr)) {
+		return err
+	}
+
+	zones, err := settings.zoneFetcherClient.zones(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	batch := &changeBatch{
+		settings:   settings,
+		zones:      zones,
+		rrSetIndex: newRRSetIndex(settings.rrSetFetcherClient, p.logger),
+	}
+	if settings.dryRun {
+		batch.dryRunDiff = newDryRunDiff()
+	}
+
+	updates, deletes, blocked, err := p.guardChanges(ctx, client, changes, batch)
+	if err != nil {
+		return err
+	}
+
+	if batch.dryRunDiff != nil {
+		defer func() {
+			p.storeDryRunReport(batch.dryRunDiff.report(blocked, massDeletionErr))
+		}()
+	}
+
 	// create rr set. POST /zones/{zoneId}/rrset
-	err = p.createRRSets(ctx, client, changes.Create)
+	err = p.createRRSets(ctx, client, changes.Create, batch)
 	if err != nil {
 		return err
 	}
 
 	// update rr set. PATCH /zones/{zoneId}/rrset/{rrSetId}
-	err = p.updateRRSets(ctx, client, changes.UpdateNew)
+	err = p.updateRRSets(ctx, client, updates, batch)
 	if err != nil {
 		return err
 	}
 
 	// delete rr set. DELETE /zones/{zoneId}/rrset/{rrSetId}
-	err = p.deleteRRSets(ctx, client, changes.Delete)
+	err = p.deleteRRSets(ctx, client, deletes, batch)
 	if err != nil {
 		return err
 	}
 
+	if undone := batch.undone.Load(); undone > 0 {
+		return fmt.Errorf("%w, %d change(s) left undone", ErrDraining, undone)
+	}
+
+	if massDeletionErr != nil {
+		return massDeletionErr
+	}
+
+	if len(blocked) > 0 {
+		return &BlockedChangesError{Changes: blocked}
+	}
+
 	return nil
 }
 
+// guardChanges removes updates and deletions of record sets that are not owned by external-dns from the changes.
+func (p *Provider) guardChanges(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	changes *plan.Changes,
+	batch *changeBatch,
+) ([]*endpoint.Endpoint, []*endpoint.Endpoint, []*ChangeBlockedError, error) {
+	ownershipGuard := batch.settings.ownershipGuard
+	if !ownershipGuard.config.enabled() || len(changes.UpdateNew)+len(changes.Delete) == 0 {
+		return changes.UpdateNew, changes.Delete, nil, nil
+	}
+
+	ownedNames := make(map[string]map[string]struct{})
+
+	updates, blockedUpdates, err := ownershipGuard.filter(
+		ctx, client, batch.rrSetIndex, changes.UpdateNew, batch.zones, UPDATE, ownedNames,
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	deletes, blockedDeletes, err := ownershipGuard.filter(
+		ctx, client, batch.rrSetIndex, changes.Delete, batch.zones, DELETE, ownedNames,
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return updates, deletes, append(blockedUpdates, blockedDeletes...), nil
+}
+
+// storeDryRunReport logs the diff computed in dry-run mode and keeps it for DryRunReport.
+func (p *Provider) storeDryRunReport(report *DryRunReport) {
+	p.logger.Info("dry run report", zap.Any("report", report))
+	p.lastDryRunReport.Store(report)
+}
+
+// DryRunReport returns the *DryRunReport computed by the last ApplyChanges call in dry-run mode
+// or nil if there was none.
+func (p *Provider) DryRunReport() any {
+	report := p.lastDryRunReport.Load()
+	if report == nil {
+		return nil
+	}
+
+	return report
+}
+
 // createRRSets creates new record sets for the given endpoints that are in the creation field.
 func (p *Provider) createRRSets(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	endpoints []*endpoint.Endpoint,
+	batch *changeBatch,
 ) error {
 	if len(endpoints) == 0 {
 		return nil
 	}
 
-	return p.handleRRSetWithWorkers(ctx, client, endpoints, CREATE)
+	return p.handleRRSetWithWorkers(ctx, client, endpoints, CREATE, batch)
 }
 
 // updateRRSets patches (overrides) contents in the record sets for the given endpoints that are in the update new field.
@@ -56,12 +170,13 @@ func (p *Provider) updateRRSets(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	endpoints []*endpoint.Endpoint,
+	batch *changeBatch,
 ) error {
 	if len(endpoints) == 0 {
 		return nil
 	}
 
-	return p.handleRRSetWithWorkers(ctx, client, endpoints, UPDATE)
+	return p.handleRRSetWithWorkers(ctx, client, endpoints, UPDATE, batch)
 }
 
 // deleteRRSets delete record sets for the given endpoints that are in the deletion field.
@@ -69,51 +184,49 @@ func (p *Provider) deleteRRSets(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	endpoints []*endpoint.Endpoint,
+	batch *changeBatch,
 ) error {
 	if len(endpoints) == 0 {
 		return nil
 	}
 
-	p.logger.Info("records to delete", zap.String("records", fmt.Sprintf("%v", endpoints)))
+	if entry := p.logger.Check(zap.InfoLevel, "records to delete"); entry != nil {
+		entry.Write(zap.String("records", fmt.Sprintf("%v", endpoints)))
+	}
 
-	return p.handleRRSetWithWorkers(ctx, client, endpoints, DELETE)
+	return p.handleRRSetWithWorkers(ctx, client, endpoints, DELETE, batch)
 }
 
-// handleRRSetWithWorkers handles the given endpoints with workers to optimize speed.
+// handleRRSetWithWorkers handles the given endpoints with workers to optimize speed. The first failing change
+// cancels the requests of the other changes. Once the grace period of Drain ended, the remaining changes are skipped.
 func (p *Provider) handleRRSetWithWorkers(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	endpoints []*endpoint.Endpoint,
 	action string,
+	batch *changeBatch,
 ) error {
-	zones, err := p.zoneFetcherClient.zones(ctx, client)
-	if err != nil {
-		return err
-	}
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(max(1, batch.settings.workers))
 
-	workerChannel := make(chan changeTask, len(endpoints))
-	defer close(workerChannel)
-	errorChannel := make(chan error, len(endpoints))
+	for _, change := range endpoints {
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
 
-	for i := 0; i < p.workers; i++ {
-		go p.changeWorker(ctx, client, workerChannel, errorChannel, zones)
-	}
+			if p.drainer.stopped() {
+				p.drainer.skip(action, change)
+				batch.undone.Add(1)
 
-	for _, change := range endpoints {
-		workerChannel <- changeTask{
-			action: action,
-			change: change,
-		}
-	}
+				return nil
+			}
 
-	for i := 0; i < len(endpoints); i++ {
-		err := <-errorChannel
-		if err != nil {
-			return err
-		}
+			return p.handleChange(groupCtx, client, changeTask{action: action, change: change}, batch)
+		})
 	}
 
-	return nil
+	return group.Wait()
 }
 
 // createRRSet creates a new record set for the given endpoint.
@@ -121,9 +234,9 @@ func (p *Provider) createRRSet(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	change *endpoint.Endpoint,
-	zones []*domains.Zone,
+	batch *changeBatch,
 ) error {
-	resultZone, found := findBestMatchingZone(change.DNSName, zones)
+	resultZone, found := findBestMatchingZone(change.DNSName, batch.zones)
 	if !found {
 		return fmt.Errorf("no matching zone found for %s", change.DNSName)
 	}
@@ -131,18 +244,24 @@ func (p *Provider) createRRSet(
 	logFields := getLogFields(change, CREATE, resultZone.ID)
 	p.logger.Info("create record set", logFields...)
 
-	if p.dryRun {
+	batch.settings.modifyChange(change)
+
+	rrSet := getRRSetRecord(change)
+
+	if batch.dryRunDiff != nil {
 		p.logger.Debug("dry run, skipping", logFields...)
+		batch.dryRunDiff.add(resultZone, CREATE, nil, rrSet)
 
 		return nil
 	}
 
-	modifyChange(change)
-
-	rrSet := getRRSetRecord(change)
-
 	// ignore all errors to just retry on next run
-	_, err := client.CreateRRSet(ctx, resultZone.ID, rrSet)
+	createdRRSet, err := client.CreateRRSet(ctx, resultZone.ID, rrSet)
+	p.zoneRecords.invalidate(resultZone.ID)
+	if createdRRSet != nil {
+		rrSet.ID = createdRRSet.ID
+	}
+	p.audit(ctx, CREATE, resultZone, nil, rrSet, err)
 	if err != nil {
 		p.logger.Error("error creating record set", zap.Error(err))
 
@@ -159,11 +278,11 @@ func (p *Provider) updateRRSet(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	change *endpoint.Endpoint,
-	zones []*domains.Zone,
+	batch *changeBatch,
 ) error {
-	modifyChange(change)
+	batch.settings.modifyChange(change)
 
-	resultZone, resultRRSet, err := p.rrSetFetcherClient.getRRSetForUpdateDeletion(ctx, client, change, zones)
+	resultZone, resultRRSet, err := batch.rrSetIndex.getRRSetForUpdateDeletion(ctx, client, change, batch.zones)
 	if err != nil {
 		return err
 	}
@@ -171,15 +290,19 @@ func (p *Provider) updateRRSet(
 	logFields := getLogFields(change, UPDATE, resultRRSet.ID)
 	p.logger.Info("update record set", logFields...)
 
-	if p.dryRun {
+	rrSet := getRRSetRecord(change)
+
+	if batch.dryRunDiff != nil {
 		p.logger.Debug("dry run, skipping", logFields...)
+		rrSet.ID = resultRRSet.ID
+		batch.dryRunDiff.add(resultZone, UPDATE, resultRRSet, rrSet)
 
 		return nil
 	}
 
-	rrSet := getRRSetRecord(change)
-
 	err = client.UpdateRRSet(ctx, resultZone.ID, resultRRSet.ID, rrSet)
+	p.zoneRecords.invalidate(resultZone.ID)
+	p.audit(ctx, UPDATE, resultZone, resultRRSet, rrSet, err)
 	if err != nil {
 		p.logger.Error("error updating record set", zap.Error(err))
 
@@ -196,11 +319,11 @@ func (p *Provider) deleteRRSet(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
 	change *endpoint.Endpoint,
-	zones []*domains.Zone,
+	batch *changeBatch,
 ) error {
-	modifyChange(change)
+	batch.settings.modifyChange(change)
 
-	resultZone, resultRRSet, err := p.rrSetFetcherClient.getRRSetForUpdateDeletion(ctx, client, change, zones)
+	resultZone, resultRRSet, err := batch.rrSetIndex.getRRSetForUpdateDeletion(ctx, client, change, batch.zones)
 	if err != nil {
 		return err
 	}
@@ -208,13 +331,16 @@ func (p *Provider) deleteRRSet(
 	logFields := getLogFields(change, DELETE, resultRRSet.ID)
 	p.logger.Info("delete record set", logFields...)
 
-	if p.dryRun {
+	if batch.dryRunDiff != nil {
 		p.logger.Debug("dry run, skipping", logFields...)
+		batch.dryRunDiff.add(resultZone, DELETE, resultRRSet, nil)
 
 		return nil
 	}
 
 	err = client.DeleteRRSet(ctx, resultZone.ID, resultRRSet.ID)
+	p.zoneRecords.invalidate(resultZone.ID)
+	p.audit(ctx, DELETE, resultZone, resultRRSet, nil, err)
 	if err != nil {
 		p.logger.Error("error deleting record set", zap.Error(err))
 
@@ -226,27 +352,21 @@ func (p *Provider) deleteRRSet(
 	return nil
 }
 
-// changeWorker is a worker that handles changes passed by a channel.
-func (p *Provider) changeWorker(
+// handleChange handles a single change.
+func (p *Provider) handleChange(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
-	changes chan changeTask,
-	errorChannel chan error,
-	zones []*domains.Zone,
-) {
-	for change := range changes {
-		switch change.action {
-		case CREATE:
-			err := p.createRRSet(ctx, client, change.change, zones)
-			errorChannel <- err
-		case UPDATE:
-			err := p.updateRRSet(ctx, client, change.change, zones)
-			errorChannel <- err
-		case DELETE:
-			err := p.deleteRRSet(ctx, client, change.change, zones)
-			errorChannel <- err
-		}
-	}
-
-	p.logger.Debug("change worker finished")
+	task changeTask,
+	batch *changeBatch,
+) error {
+	switch task.action {
+	case CREATE:
+		return p.createRRSet(ctx, client, task.change, batch)
+	case UPDATE:
+		return p.updateRRSet(ctx, client, task.change, batch)
+	case DELETE:
+		return p.deleteRRSet(ctx, client, task.change, batch)
+	default:
+		return fmt.Errorf("unknown action %s", task.action)
+	}
 }
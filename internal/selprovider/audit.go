@@ -0,0 +1,117 @@
+package selprovider
+
+import (
+	"context"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+	"go.uber.org/zap"
+)
+
+const (
+	auditResultSuccess = "success"
+	auditResultFailure = "failure"
+)
+
+// AuditEvent is a single mutation of the Selectel DNS written to the audit log. The schema is stable:
+// fields may be added, but are never renamed or removed.
+type AuditEvent struct {
+	Timestamp time.Time   `json:"timestamp"`
+	RequestID string      `json:"requestId,omitempty"`
+	Action    string      `json:"action"`
+	ZoneID    string      `json:"zoneId"`
+	ZoneName  string      `json:"zoneName"`
+	RRSetID   string      `json:"rrSetId,omitempty"`
+	Before    *AuditRRSet `json:"before,omitempty"`
+	After     *AuditRRSet `json:"after,omitempty"`
+	Result    string      `json:"result"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// AuditRRSet is the content of a record set before or after the mutation.
+type AuditRRSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	Records []string `json:"records"`
+}
+
+// AuditSink receives an AuditEvent for every create, update and delete of a record set.
+type AuditSink interface {
+	Write(event *AuditEvent) error
+}
+
+// LoggerAuditSink writes the audit events to a dedicated logger.
+type LoggerAuditSink struct {
+	logger *zap.Logger
+}
+
+// NewLoggerAuditSink returns an AuditSink writing every event as a single log line of the given logger.
+func NewLoggerAuditSink(logger *zap.Logger) *LoggerAuditSink {
+	return &LoggerAuditSink{logger: logger}
+}
+
+func (s *LoggerAuditSink) Write(event *AuditEvent) error {
+	s.logger.Info("audit", zap.Any("event", event))
+
+	return nil
+}
+
+// audit writes an AuditEvent for the mutation of a record set if an audit sink is configured.
+func (p *Provider) audit(
+	ctx context.Context,
+	action string,
+	zone *domains.Zone,
+	before, after *domains.RRSet,
+	err error,
+) {
+	if p.auditSink == nil {
+		return
+	}
+
+	event := &AuditEvent{
+		Timestamp: time.Now().UTC(),
+		RequestID: requestid.FromContext(ctx),
+		Action:    action,
+		ZoneID:    zone.ID,
+		ZoneName:  zone.Name,
+		Before:    getAuditRRSet(before),
+		After:     getAuditRRSet(after),
+		Result:    auditResultSuccess,
+	}
+
+	if before != nil {
+		event.RRSetID = before.ID
+	} else if after != nil {
+		event.RRSetID = after.ID
+	}
+
+	if err != nil {
+		event.Result = auditResultFailure
+		event.Error = err.Error()
+	}
+
+	if writeErr := p.auditSink.Write(event); writeErr != nil {
+		p.logger.Error("error writing audit event", zap.Error(writeErr), zap.String("action", action))
+	}
+}
+
+// getAuditRRSet returns the audit representation of a record set.
+func getAuditRRSet(rrSet *domains.RRSet) *AuditRRSet {
+	if rrSet == nil {
+		return nil
+	}
+
+	records := make([]string, 0, len(rrSet.Records))
+	for _, record := range rrSet.Records {
+		records = append(records, record.Content)
+	}
+
+	return &AuditRRSet{
+		Name:    rrSet.Name,
+		Type:    string(rrSet.Type),
+		TTL:     rrSet.TTL,
+		Records: records,
+	}
+}
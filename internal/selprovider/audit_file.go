@@ -0,0 +1,119 @@
+package selprovider
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/goccy/go-json"
+)
+
+// FileAuditSink appends the audit events as JSON lines to a file. The file is rotated when it exceeds
+// the maximum size: path is renamed to path.1, path.1 to path.2 and so on, the oldest backup is removed. At least one
+// backup is kept, so the events written before a rotation are never lost with it.
+type FileAuditSink struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewFileAuditSink opens or creates the audit log file at path. A maxSize of 0 disables the rotation, a maxBackups
+// below 1 keeps a single backup.
+func NewFileAuditSink(path string, maxSize int64, maxBackups int) (*FileAuditSink, error) {
+	sink := &FileAuditSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: max(maxBackups, 1),
+	}
+
+	err := sink.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+func (s *FileAuditSink) Write(event *AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		err = s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the audit log file.
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// open opens the audit log file in append-only mode.
+func (s *FileAuditSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+// rotate moves the current audit log file to the first backup and opens a new one.
+func (s *FileAuditSink) rotate() error {
+	err := s.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+
+	for i := s.maxBackups - 1; i > 0; i-- {
+		err = os.Rename(s.backupPath(i), s.backupPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	err = os.Rename(s.path, s.backupPath(1))
+	if err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return s.open()
+}
+
+// backupPath returns the path of the n-th backup of the audit log.
+func (s *FileAuditSink) backupPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.path, n)
+}
@@ -0,0 +1,174 @@
+package selprovider
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+type recordingAuditSink struct {
+	mu     sync.Mutex
+	events []*AuditEvent
+}
+
+func (s *recordingAuditSink) Write(event *AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+func TestAuditEvents(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	mux.HandleFunc("/zones/1234/rrset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "9999", "name": "new.test.com.", "type": "A", "ttl": 300}`))
+
+			return
+		}
+		getRrsetsResponseRecords(t, w, "1234")
+	})
+	mux.HandleFunc("/zones/5678/rrset", func(w http.ResponseWriter, r *http.Request) {
+		getRrsetsResponseRecords(t, w, "5678")
+	})
+	mux.HandleFunc("/zones/1234/rrset/1234", responseHandler(nil, http.StatusNoContent))
+	mux.HandleFunc("/zones/5678/rrset/5678", responseHandler([]byte(`{"error": "forbidden"}`), http.StatusForbidden))
+
+	sink := &recordingAuditSink{}
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          1,
+		AuditSink:        sink,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx := requestid.NewContext(context.Background(), "request-1")
+	err = dnsProvider.ApplyChanges(ctx, &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "new.test.com", Targets: endpoint.Targets{"9.9.9.9"}, RecordType: "A"},
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			{DNSName: "test.com", Targets: endpoint.Targets{"4.3.2.1"}, RecordType: "A"},
+		},
+		Delete: []*endpoint.Endpoint{
+			{DNSName: "test2.com", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+		},
+	})
+	assert.Error(t, err)
+
+	sort.Slice(sink.events, func(i, j int) bool {
+		return sink.events[i].Action < sink.events[j].Action
+	})
+	assert.Len(t, sink.events, 3)
+
+	created := sink.events[0]
+	assert.Equal(t, CREATE, created.Action)
+	assert.Equal(t, "request-1", created.RequestID)
+	assert.Equal(t, "9999", created.RRSetID)
+	assert.Nil(t, created.Before)
+	assert.Equal(t, []string{"9.9.9.9"}, created.After.Records)
+	assert.Equal(t, auditResultSuccess, created.Result)
+
+	deleted := sink.events[1]
+	assert.Equal(t, DELETE, deleted.Action)
+	assert.Equal(t, "5678", deleted.ZoneID)
+	assert.Equal(t, []string{"5.6.7.8"}, deleted.Before.Records)
+	assert.Nil(t, deleted.After)
+	assert.Equal(t, auditResultFailure, deleted.Result)
+	assert.NotEmpty(t, deleted.Error)
+
+	updated := sink.events[2]
+	assert.Equal(t, UPDATE, updated.Action)
+	assert.Equal(t, "1234", updated.RRSetID)
+	assert.Equal(t, []string{"1.2.3.4"}, updated.Before.Records)
+	assert.Equal(t, []string{"4.3.2.1"}, updated.After.Records)
+	assert.Equal(t, auditResultSuccess, updated.Result)
+}
+
+func TestFileAuditSinkRotation(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	event := &AuditEvent{
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Action:    CREATE,
+		ZoneID:    "1234",
+		Result:    auditResultSuccess,
+	}
+	line, err := json.Marshal(event)
+	assert.NoError(t, err)
+
+	// every file holds two events
+	sink, err := NewFileAuditSink(path, int64(2*(len(line)+1)), 2)
+	assert.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		assert.NoError(t, sink.Write(event))
+	}
+	assert.NoError(t, sink.Close())
+
+	assert.Equal(t, 1, countAuditLines(t, path))
+	assert.Equal(t, 2, countAuditLines(t, path+".1"))
+	assert.Equal(t, 2, countAuditLines(t, path+".2"))
+	assert.NoFileExists(t, path+".3")
+
+	// the file is never removed without a backup
+	path = filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err = NewFileAuditSink(path, int64(2*(len(line)+1)), 0)
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sink.Write(event))
+	}
+	assert.NoError(t, sink.Close())
+
+	assert.Equal(t, 1, countAuditLines(t, path))
+	assert.Equal(t, 2, countAuditLines(t, path+".1"))
+	assert.NoFileExists(t, path+".2")
+}
+
+func countAuditLines(t *testing.T, path string) int {
+	t.Helper()
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event AuditEvent
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		count++
+	}
+
+	return count
+}
@@ -0,0 +1,215 @@
+package selprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// CheckPassed is the status of a passed step of Check.
+	CheckPassed = "pass"
+	// CheckFailed is the status of a failed step of Check.
+	CheckFailed = "fail"
+	// CheckSkipped is the status of a step of Check skipped because of a failed step before.
+	CheckSkipped = "skip"
+)
+
+const (
+	checkStepAuthenticate    = "authenticate"
+	checkStepListZones       = "list zones"
+	checkStepDomainFilter    = "domain filter"
+	checkStepWritePermission = "write permission"
+
+	// checkCanaryPrefix is the prefix of the name of the canary record set created to check the write permission.
+	checkCanaryPrefix = "external-dns-selectel-webhook-check-"
+	// checkListedZones is the maximum number of zones listed in the report.
+	checkListedZones = 5
+)
+
+// CheckResult is the result of a single step of Check.
+type CheckResult struct {
+	Step    string `json:"step"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// CheckReport lists the results of the steps of Check in the order they were run.
+type CheckReport struct {
+	Results []*CheckResult `json:"results"`
+}
+
+// Passed reports whether no step failed.
+func (r *CheckReport) Passed() bool {
+	for _, result := range r.Results {
+		if result.Status != CheckPassed {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *CheckReport) add(step, status, message string) {
+	r.Results = append(r.Results, &CheckResult{Step: step, Status: status, Message: message})
+}
+
+// skip adds the given steps as skipped because of the failed step.
+func (r *CheckReport) skip(failedStep string, steps ...string) {
+	for _, step := range steps {
+		r.add(step, CheckSkipped, fmt.Sprintf("%s failed", failedStep))
+	}
+}
+
+// Check verifies the configuration of the provider against the Selectel DNS API. It authenticates with Keystone,
+// lists the managed zones and verifies that every entry of the domain filter matches one of them. With write, a
+// canary TXT record set is created and deleted in the first managed zone to prove the write permission. The dry-run
+// mode does not apply to the canary.
+func (p *Provider) Check(ctx context.Context, write bool) *CheckReport {
+	report := &CheckReport{}
+
+	remaining := []string{checkStepListZones, checkStepDomainFilter}
+	if write {
+		remaining = append(remaining, checkStepWritePermission)
+	}
+
+	token, err := p.keystoneProvider.GetToken(ctx)
+	if err != nil {
+		report.add(checkStepAuthenticate, CheckFailed, err.Error())
+		report.skip(checkStepAuthenticate, remaining...)
+
+		return report
+	}
+	report.add(checkStepAuthenticate, CheckPassed, "token issued by Keystone")
+
+	client := p.newDomainsClient(token)
+	settings := p.settings.Load()
+
+	zones, err := settings.zoneFetcherClient.zones(ctx, client)
+	if err != nil {
+		report.add(checkStepListZones, CheckFailed, err.Error())
+		report.skip(checkStepListZones, remaining[1:]...)
+
+		return report
+	}
+	report.add(checkStepListZones, CheckPassed, describeZones(zones))
+
+	if err := checkDomainFilter(settings.domainFilter, zones); err != nil {
+		report.add(checkStepDomainFilter, CheckFailed, err.Error())
+		if len(zones) == 0 {
+			report.skip(checkStepDomainFilter, remaining[2:]...)
+
+			return report
+		}
+	} else {
+		report.add(checkStepDomainFilter, CheckPassed, "every domain filter entry matches a managed zone")
+	}
+
+	if write {
+		if err := p.checkWritePermission(ctx, client, zones[0]); err != nil {
+			report.add(checkStepWritePermission, CheckFailed, err.Error())
+		} else {
+			report.add(checkStepWritePermission, CheckPassed, fmt.Sprintf(
+				"canary TXT record set created and deleted in zone %s", zones[0].Name,
+			))
+		}
+	}
+
+	return report
+}
+
+// checkDomainFilter returns an error if no zone is managed or an include entry of the domain filter matches none of
+// the zones.
+func checkDomainFilter(domainFilter endpoint.DomainFilter, zones []*domains.Zone) error {
+	if len(zones) == 0 {
+		return fmt.Errorf("no zone is managed, check the domain and zone filters")
+	}
+
+	var unmatched []string
+	for _, filter := range domainFilter.Filters {
+		if filter == "" {
+			continue
+		}
+
+		entryFilter := endpoint.NewDomainFilter([]string{filter})
+		matched := false
+		for _, zone := range zones {
+			if entryFilter.Match(zone.Name) {
+				matched = true
+
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, filter)
+		}
+	}
+
+	if len(unmatched) > 0 {
+		return fmt.Errorf("no managed zone matches the domain filter entries: %s", strings.Join(unmatched, ", "))
+	}
+
+	return nil
+}
+
+// checkWritePermission creates and deletes a canary TXT record set in the zone. Both calls are audited like every
+// other mutation.
+func (p *Provider) checkWritePermission(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	zone *domains.Zone,
+) error {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+
+	rrSet := &domains.RRSet{
+		Name:    checkCanaryPrefix + hex.EncodeToString(suffix) + "." + zone.Name,
+		Type:    domains.TXT,
+		TTL:     60,
+		Records: []domains.RecordItem{{Content: `"external-dns-selectel-webhook write permission check"`}},
+	}
+
+	created, err := client.CreateRRSet(ctx, zone.ID, rrSet)
+	p.zoneRecords.invalidate(zone.ID)
+	if created != nil {
+		rrSet.ID = created.ID
+	}
+	p.audit(ctx, CREATE, zone, nil, rrSet, err)
+	if err != nil {
+		return fmt.Errorf("failed to create canary record set %s: %w", rrSet.Name, err)
+	}
+
+	err = client.DeleteRRSet(ctx, zone.ID, rrSet.ID)
+	p.zoneRecords.invalidate(zone.ID)
+	p.audit(ctx, DELETE, zone, rrSet, nil, err)
+	if err != nil {
+		return fmt.Errorf("created canary record set %s but failed to delete it, remove it manually: %w", rrSet.Name, err)
+	}
+
+	return nil
+}
+
+// describeZones returns the number of zones and the first of their names.
+func describeZones(zones []*domains.Zone) string {
+	names := make([]string, 0, min(len(zones), checkListedZones))
+	for _, zone := range zones[:min(len(zones), checkListedZones)] {
+		names = append(names, zone.Name)
+	}
+
+	description := fmt.Sprintf("%d zones managed", len(zones))
+	if len(names) > 0 {
+		description += ": " + strings.Join(names, ", ")
+	}
+	if len(zones) > checkListedZones {
+		description += fmt.Sprintf(" and %d more", len(zones)-checkListedZones)
+	}
+
+	return description
+}
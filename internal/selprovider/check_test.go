@@ -0,0 +1,126 @@
+package selprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	mock_selprovider "github.com/selectel/external-dns-selectel-webhook/internal/selprovider/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+
+	// record sets can not be created in the read-only zone
+	readOnly, err := fake.AddZone("read-only.org")
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == fakeselectel.DomainsPath+"/zones/"+readOnly.ID+"/rrset" {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// the canary record sets are deleted, checked after the parallel subtests
+	t.Cleanup(func() {
+		assert.Empty(t, fake.RRSets(zone.ID))
+	})
+
+	tests := []struct {
+		name             string
+		domainFilter     []string
+		write            bool
+		expectedStatuses []string
+	}{
+		{
+			name:             "Read checks pass",
+			domainFilter:     []string{"example.com"},
+			expectedStatuses: []string{CheckPassed, CheckPassed, CheckPassed},
+		},
+		{
+			name:             "Write check passes",
+			domainFilter:     []string{"example.com"},
+			write:            true,
+			expectedStatuses: []string{CheckPassed, CheckPassed, CheckPassed, CheckPassed},
+		},
+		{
+			name:             "Domain filter entry matches no zone",
+			domainFilter:     []string{"example.com", "missing.org"},
+			write:            true,
+			expectedStatuses: []string{CheckPassed, CheckPassed, CheckFailed, CheckPassed},
+		},
+		{
+			name:             "No zone managed",
+			domainFilter:     []string{"missing.org"},
+			write:            true,
+			expectedStatuses: []string{CheckPassed, CheckPassed, CheckFailed, CheckSkipped},
+		},
+		{
+			name:             "Write check fails",
+			domainFilter:     []string{"read-only.org"},
+			write:            true,
+			expectedStatuses: []string{CheckPassed, CheckPassed, CheckPassed, CheckFailed},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dnsProvider, err := New(Config{
+				BaseURL:          server.URL + fakeselectel.DomainsPath,
+				KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+				DomainFilter:     endpoint.NewDomainFilter(tt.domainFilter),
+				Workers:          1,
+			}, zap.NewNop())
+			assert.NoError(t, err)
+
+			report := dnsProvider.Check(context.Background(), tt.write)
+			statuses := make([]string, 0, len(report.Results))
+			for _, result := range report.Results {
+				statuses = append(statuses, result.Status)
+			}
+			assert.Equal(t, tt.expectedStatuses, statuses)
+			assert.Equal(t, !slices.Contains(statuses, CheckFailed), report.Passed())
+		})
+	}
+}
+
+func TestCheckAuthenticationFails(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	keystoneProvider := mock_selprovider.NewMockKeystoneProvider(ctrl)
+	keystoneProvider.EXPECT().GetToken(gomock.Any()).Return("", errors.New("invalid credentials"))
+
+	dnsProvider, err := New(Config{KeystoneProvider: keystoneProvider}, zap.NewNop())
+	assert.NoError(t, err)
+
+	report := dnsProvider.Check(context.Background(), true)
+	assert.False(t, report.Passed())
+	assert.Equal(t, []*CheckResult{
+		{Step: checkStepAuthenticate, Status: CheckFailed, Message: "invalid credentials"},
+		{Step: checkStepListZones, Status: CheckSkipped, Message: "authenticate failed"},
+		{Step: checkStepDomainFilter, Status: CheckSkipped, Message: "authenticate failed"},
+		{Step: checkStepWritePermission, Status: CheckSkipped, Message: "authenticate failed"},
+	}, report.Results)
+}
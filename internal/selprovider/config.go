@@ -1,6 +1,11 @@
 package selprovider
 
 import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -8,17 +13,42 @@ import (
 type Config struct {
 	// BaseURL is a Selectel DNS API endpoint for v2.DNSClient
 	BaseURL string
+	// HTTPClient is shared by all requests to the DNS API. The default client of httpdefault is used if it is nil.
+	HTTPClient *http.Client
 	// KeystoneProvider needed to generate X-Auth-Token header with keystone-header for requests to the DNS API.
 	KeystoneProvider KeystoneProvider
 	// DomainFilter is a list with domains that will be affected. If it is empty all available domains will be affected.
 	DomainFilter endpoint.DomainFilter
+	// ZoneFilter selects the affected zones by their id and state.
+	ZoneFilter ZoneFilterConfig
+	// ZoneTTLs are the TTLs of the record sets by zone name, used if external-dns does not set a TTL. The default
+	// TTL of the provider is used for the record sets of other zones.
+	ZoneTTLs map[string]int
 	// DryRun is a flag specifies user's wish to run without requests to the DNS API
 	DryRun bool
 	// Workers is a number of goroutines that will create requests to the DNS API.
 	Workers int
+	// RecordsCacheTTL is the time the result of Records is reused for. Concurrent calls share a single fetch
+	// regardless of it.
+	RecordsCacheTTL time.Duration
+	// FullResyncInterval enables incremental records. The record sets of a zone are only read again if the zone was
+	// modified since the last scan, and all zones are read at least once per interval. 0 reads all zones on every
+	// call.
+	FullResyncInterval time.Duration
+	// ZoneRecordsMaxAge is the maximum time the records of a zone are served from the cache of incremental records,
+	// even if the modification time of the zone did not change. 0 uses FullResyncInterval.
+	ZoneRecordsMaxAge time.Duration
+	// OwnershipGuard protects record sets not managed by external-dns from updates and deletions.
+	OwnershipGuard OwnershipGuardConfig
+	// MassDeletionGuard refuses batches of changes deleting too many records at once.
+	MassDeletionGuard MassDeletionGuardConfig
+	// AuditSink receives an event for every mutation of the Selectel DNS. The audit log is disabled if it is nil.
+	AuditSink AuditSink
+	// Metrics collects metrics of the provider safety guards. No metrics are collected if it is nil.
+	Metrics metrics.ProviderMetrics
 }
 
 //go:generate mockgen -destination=./mock/keystone_provider.go -source=./config.go KeystoneProvider
 type KeystoneProvider interface {
-	GetToken() (string, error)
+	GetToken(ctx context.Context) (string, error)
 }
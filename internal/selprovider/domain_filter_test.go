@@ -1,9 +1,11 @@
 package selprovider
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
@@ -19,3 +21,16 @@ func TestGetDomainFilter(t *testing.T) {
 	domainFilter := dnsProvider.GetDomainFilter()
 	assert.Equal(t, domainFilter, endpoint.DomainFilter{})
 }
+
+func TestGetDomainFilterAdvertisesExclusions(t *testing.T) {
+	t.Parallel()
+
+	dnsProvider, err := New(Config{
+		DomainFilter: endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"sub.example.com"}),
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(dnsProvider.GetDomainFilter())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"include":["example.com"],"exclude":["sub.example.com"]}`, string(data))
+}
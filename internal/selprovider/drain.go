@@ -0,0 +1,145 @@
+package selprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// ErrDraining is returned by ApplyChanges once Drain was called. The changes are applied by the next run of
+// external-dns against the restarted webhook.
+var ErrDraining = errors.New("provider is shutting down")
+
+// defaultDrainStopTimeout is the time given to the running calls to finish the changes already sent to the API once
+// the grace period of Drain ended, like the default HTTP timeout.
+const defaultDrainStopTimeout = 30 * time.Second
+
+// UndoneChangesError is returned by Drain when the running ApplyChanges calls were stopped before all of their
+// changes were applied.
+type UndoneChangesError struct {
+	Changes []string
+}
+
+func (e *UndoneChangesError) Error() string {
+	return fmt.Sprintf("%d change(s) left undone: %s", len(e.Changes), strings.Join(e.Changes, "; "))
+}
+
+// UndoneChanges returns a human-readable description of every change left undone.
+func (e *UndoneChangesError) UndoneChanges() []string {
+	return e.Changes
+}
+
+// drainer tracks the running ApplyChanges calls, so they can be finished or stopped on shutdown.
+type drainer struct {
+	mu       sync.Mutex
+	draining bool
+	running  sync.WaitGroup
+	undone   []string
+
+	// stop is closed when the grace period of Drain ended, the running batches stop before their next change
+	stop        chan struct{}
+	stopOnce    sync.Once
+	stopTimeout time.Duration
+}
+
+func newDrainer() *drainer {
+	return &drainer{
+		stop:        make(chan struct{}),
+		stopTimeout: defaultDrainStopTimeout,
+	}
+}
+
+// start registers a running batch. It returns false if the provider is draining.
+func (d *drainer) start() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return false
+	}
+	d.running.Add(1)
+
+	return true
+}
+
+func (d *drainer) done() {
+	d.running.Done()
+}
+
+// stopped reports whether the grace period ended and no further change may be started.
+func (d *drainer) stopped() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// skip records a change that was not started because the grace period ended.
+func (d *drainer) skip(action string, change *endpoint.Endpoint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.undone = append(d.undone, fmt.Sprintf("%s %s %s", action, change.DNSName, change.RecordType))
+}
+
+// Drain refuses new ApplyChanges calls and waits for the running ones until the context is done. Then the running
+// calls finish the changes already sent to the API and skip the remaining ones, so every record set is either
+// changed completely or left as it was. The skipped changes are returned as *UndoneChangesError. If the running calls
+// do not finish within the stop timeout, Drain gives up on them and returns an error.
+func (p *Provider) Drain(ctx context.Context) error {
+	d := p.drainer
+
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+
+	timer := time.NewTimer(d.stopTimeout)
+	defer timer.Stop()
+
+	stoppedInTime := true
+	select {
+	case <-done:
+	case <-timer.C:
+		stoppedInTime = false
+	}
+
+	d.mu.Lock()
+	undone := slices.Clone(d.undone)
+	d.mu.Unlock()
+
+	if !stoppedInTime {
+		return fmt.Errorf(
+			"running changes did not stop within %s, %d change(s) left undone so far", d.stopTimeout, len(undone),
+		)
+	}
+
+	if len(undone) == 0 {
+		return nil
+	}
+
+	return &UndoneChangesError{Changes: undone}
+}
@@ -0,0 +1,168 @@
+package selprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		gracePeriod    time.Duration
+		expectedRRSets int
+		expectedUndone []string
+	}{
+		{
+			name:           "Running changes finish within the grace period",
+			gracePeriod:    time.Minute,
+			expectedRRSets: 3,
+		},
+		{
+			name:           "Remaining changes are skipped after the grace period",
+			gracePeriod:    50 * time.Millisecond,
+			expectedRRSets: 1,
+			expectedUndone: []string{"CREATE b.example.com A", "CREATE c.example.com A"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fake := fakeselectel.New(fakeselectel.Config{})
+			fake.AddToken("test")
+			zone, err := fake.AddZone("example.com")
+			assert.NoError(t, err)
+
+			// the first created record set is held until it is released
+			arrived := make(chan struct{})
+			release := make(chan struct{})
+			first := true
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPost && first {
+					first = false
+					close(arrived)
+					<-release
+				}
+				fake.ServeHTTP(w, r)
+			}))
+			t.Cleanup(server.Close)
+
+			dnsProvider, err := New(Config{
+				BaseURL:          server.URL + fakeselectel.DomainsPath,
+				KeystoneProvider: getDefaultKeystoneProvider(t, 2),
+				DomainFilter:     endpoint.NewDomainFilter([]string{"example.com"}),
+				Workers:          1,
+			}, zap.NewNop())
+			assert.NoError(t, err)
+
+			applied := make(chan error, 1)
+			go func() {
+				applied <- dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+					Create: []*endpoint.Endpoint{
+						{DNSName: "a.example.com", RecordType: "A", Targets: endpoint.Targets{"1.1.1.1"}},
+						{DNSName: "b.example.com", RecordType: "A", Targets: endpoint.Targets{"2.2.2.2"}},
+						{DNSName: "c.example.com", RecordType: "A", Targets: endpoint.Targets{"3.3.3.3"}},
+					},
+				})
+			}()
+			<-arrived
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.gracePeriod)
+			defer cancel()
+
+			go func() {
+				// within the grace period unless it is shorter than the delay
+				time.Sleep(100 * time.Millisecond)
+				close(release)
+			}()
+
+			err = dnsProvider.Drain(ctx)
+			applyErr := <-applied
+			if tt.expectedUndone == nil {
+				assert.NoError(t, err)
+				assert.NoError(t, applyErr)
+			} else {
+				var undoneErr *UndoneChangesError
+				assert.ErrorAs(t, err, &undoneErr)
+				assert.ElementsMatch(t, tt.expectedUndone, undoneErr.UndoneChanges())
+				assert.ErrorIs(t, applyErr, ErrDraining)
+			}
+			assert.Len(t, fake.RRSets(zone.ID), tt.expectedRRSets)
+
+			// new calls are refused
+			assert.ErrorIs(t, dnsProvider.ApplyChanges(context.Background(), &plan.Changes{}), ErrDraining)
+		})
+	}
+}
+
+func TestDrainStopTimeout(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	_, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+
+	// the created record set hangs until the test ends
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			close(arrived)
+			<-release
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		close(release)
+	})
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.NewDomainFilter([]string{"example.com"}),
+		Workers:          1,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+	dnsProvider.drainer.stopTimeout = 50 * time.Millisecond
+
+	go dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "a.example.com", RecordType: "A", Targets: endpoint.Targets{"1.1.1.1"}},
+		},
+	})
+	<-arrived
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = dnsProvider.Drain(ctx)
+	assert.ErrorContains(t, err, "did not stop within 50ms")
+}
+
+func TestDrainWithoutRunningChanges(t *testing.T) {
+	t.Parallel()
+
+	dnsProvider, err := New(Config{}, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.NoError(t, dnsProvider.Drain(ctx))
+	assert.ErrorIs(t, dnsProvider.applyChanges(context.Background(), nil, &plan.Changes{}), ErrDraining)
+}
@@ -0,0 +1,121 @@
+package selprovider
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+)
+
+// DryRunReport is the diff computed by an ApplyChanges call in dry-run mode.
+type DryRunReport struct {
+	GeneratedAt time.Time             `json:"generatedAt"`
+	Zones       []*ZoneDiff           `json:"zones"`
+	Blocked     []*ChangeBlockedError `json:"blocked,omitempty"`
+	// Refused is the reason the mass deletion breaker refused the batch, the zones list the changes it would have
+	// made otherwise.
+	Refused string `json:"refused,omitempty"`
+}
+
+// ZoneDiff lists the changes that would be made in a single zone.
+type ZoneDiff struct {
+	ZoneID   string       `json:"zoneId"`
+	ZoneName string       `json:"zoneName"`
+	Changes  []*RRSetDiff `json:"changes"`
+}
+
+// RRSetDiff describes a single change of a record set and the API call that would be made for it.
+type RRSetDiff struct {
+	Action  string         `json:"action"`
+	Request string         `json:"request"`
+	Current *domains.RRSet `json:"current,omitempty"`
+	Desired *domains.RRSet `json:"desired,omitempty"`
+}
+
+// dryRunDiff collects the changes of a single ApplyChanges call from all workers.
+type dryRunDiff struct {
+	mu    sync.Mutex
+	zones map[string]*ZoneDiff
+}
+
+func newDryRunDiff() *dryRunDiff {
+	return &dryRunDiff{
+		zones: make(map[string]*ZoneDiff),
+	}
+}
+
+// add records the change of a record set in the given zone.
+func (d *dryRunDiff) add(zone *domains.Zone, action string, current, desired *domains.RRSet) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	zoneDiff, ok := d.zones[zone.ID]
+	if !ok {
+		zoneDiff = &ZoneDiff{
+			ZoneID:   zone.ID,
+			ZoneName: zone.Name,
+		}
+		d.zones[zone.ID] = zoneDiff
+	}
+
+	zoneDiff.Changes = append(zoneDiff.Changes, &RRSetDiff{
+		Action:  action,
+		Request: getRequestLine(zone.ID, action, current),
+		Current: current,
+		Desired: desired,
+	})
+}
+
+// report returns the collected changes sorted by zone, record set name and type.
+func (d *dryRunDiff) report(blocked []*ChangeBlockedError, massDeletionErr *MassDeletionError) *DryRunReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := &DryRunReport{
+		GeneratedAt: time.Now().UTC(),
+		Zones:       make([]*ZoneDiff, 0, len(d.zones)),
+		Blocked:     blocked,
+	}
+	if massDeletionErr != nil {
+		result.Refused = massDeletionErr.Error()
+	}
+
+	for _, zoneDiff := range d.zones {
+		sort.SliceStable(zoneDiff.Changes, func(i, j int) bool {
+			return getDiffKey(zoneDiff.Changes[i]) < getDiffKey(zoneDiff.Changes[j])
+		})
+		result.Zones = append(result.Zones, zoneDiff)
+	}
+
+	sort.Slice(result.Zones, func(i, j int) bool {
+		return result.Zones[i].ZoneName < result.Zones[j].ZoneName
+	})
+
+	return result
+}
+
+// getDiffKey returns the key the changes of a zone are sorted by.
+func getDiffKey(diff *RRSetDiff) string {
+	rrSet := diff.Desired
+	if rrSet == nil {
+		rrSet = diff.Current
+	}
+
+	return rrSet.Name + " " + string(rrSet.Type) + " " + diff.Action
+}
+
+// getRequestLine returns the method and the path of the Selectel DNS API call made for the change.
+func getRequestLine(zoneID, action string, current *domains.RRSet) string {
+	switch action {
+	case CREATE:
+		return fmt.Sprintf("POST /zones/%s/rrset", zoneID)
+	case UPDATE:
+		return fmt.Sprintf("PATCH /zones/%s/rrset/%s", zoneID, current.ID)
+	case DELETE:
+		return fmt.Sprintf("DELETE /zones/%s/rrset/%s", zoneID, current.ID)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,119 @@
+package selprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestDryRunReport(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	mux.HandleFunc("/zones/1234/rrset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		getRrsetsResponseRecords(t, w, "1234")
+	})
+	mux.HandleFunc("/zones/5678/rrset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		getRrsetsResponseRecords(t, w, "5678")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request in dry run: %s %s", r.Method, r.URL.Path)
+	})
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		DryRun:           true,
+		Workers:          2,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+	assert.Nil(t, dnsProvider.DryRunReport())
+
+	err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "new.test.com", Targets: endpoint.Targets{"9.9.9.9"}, RecordType: "A"},
+		},
+		UpdateNew: []*endpoint.Endpoint{
+			{DNSName: "test.com", Targets: endpoint.Targets{"4.3.2.1"}, RecordType: "A", RecordTTL: 60},
+		},
+		Delete: []*endpoint.Endpoint{
+			{DNSName: "test2.com", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+		},
+	})
+	assert.NoError(t, err)
+
+	report, ok := dnsProvider.DryRunReport().(*DryRunReport)
+	assert.True(t, ok)
+	assert.Len(t, report.Zones, 2)
+
+	zone := report.Zones[0]
+	assert.Equal(t, "test.com", zone.ZoneName)
+	assert.Len(t, zone.Changes, 2)
+	assert.Equal(t, CREATE, zone.Changes[0].Action)
+	assert.Equal(t, "POST /zones/1234/rrset", zone.Changes[0].Request)
+	assert.Nil(t, zone.Changes[0].Current)
+	assert.Equal(t, "new.test.com.", zone.Changes[0].Desired.Name)
+	assert.Equal(t, UPDATE, zone.Changes[1].Action)
+	assert.Equal(t, "PATCH /zones/1234/rrset/1234", zone.Changes[1].Request)
+	assert.Equal(t, "1.2.3.4", zone.Changes[1].Current.Records[0].Content)
+	assert.Equal(t, "4.3.2.1", zone.Changes[1].Desired.Records[0].Content)
+	assert.Equal(t, 60, zone.Changes[1].Desired.TTL)
+
+	zone = report.Zones[1]
+	assert.Equal(t, "test2.com", zone.ZoneName)
+	assert.Len(t, zone.Changes, 1)
+	assert.Equal(t, DELETE, zone.Changes[0].Action)
+	assert.Equal(t, "DELETE /zones/5678/rrset/5678", zone.Changes[0].Request)
+	assert.Nil(t, zone.Changes[0].Desired)
+}
+
+func TestDryRunReportMassDeletion(t *testing.T) {
+	t.Parallel()
+
+	var deletes atomic.Int32
+	server := getMassDeletionGuardServer(t, &deletes)
+	defer server.Close()
+
+	dnsProvider, err := New(Config{
+		BaseURL:           server.URL,
+		KeystoneProvider:  getDefaultKeystoneProvider(t, 1),
+		DomainFilter:      endpoint.DomainFilter{},
+		DryRun:            true,
+		Workers:           1,
+		MassDeletionGuard: MassDeletionGuardConfig{MaxDeletions: 1},
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			{DNSName: "test.com", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: "A"},
+			{DNSName: "test2.com", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+		},
+	})
+	var massDeletionErr *MassDeletionError
+	assert.True(t, errors.As(err, &massDeletionErr))
+	assert.Zero(t, deletes.Load())
+
+	// the report shows the refusal and the deletions that were refused
+	report, ok := dnsProvider.DryRunReport().(*DryRunReport)
+	assert.True(t, ok)
+	assert.Equal(t, massDeletionErr.Error(), report.Refused)
+	assert.Len(t, report.Zones, 2)
+}
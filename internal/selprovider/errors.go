@@ -0,0 +1,38 @@
+package selprovider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeBlockedError describes a single change that was refused by a safety guard of the provider.
+type ChangeBlockedError struct {
+	Action     string `json:"action"`
+	DNSName    string `json:"dnsName"`
+	RecordType string `json:"recordType"`
+	Reason     string `json:"reason"`
+}
+
+func (e *ChangeBlockedError) Error() string {
+	return fmt.Sprintf("%s %s %s blocked: %s", e.Action, e.DNSName, e.RecordType, e.Reason)
+}
+
+// BlockedChangesError is returned by ApplyChanges when some changes were refused by a safety guard.
+// All other changes of the batch are applied.
+type BlockedChangesError struct {
+	Changes []*ChangeBlockedError
+}
+
+func (e *BlockedChangesError) Error() string {
+	return fmt.Sprintf("%d change(s) blocked: %s", len(e.Changes), strings.Join(e.BlockedChanges(), "; "))
+}
+
+// BlockedChanges returns a human-readable description of every blocked change.
+func (e *BlockedChangesError) BlockedChanges() []string {
+	result := make([]string, 0, len(e.Changes))
+	for _, change := range e.Changes {
+		result = append(result, change.Error())
+	}
+
+	return result
+}
@@ -0,0 +1,53 @@
+package selprovider
+
+import (
+	"context"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+)
+
+// ZoneExport is a managed zone with all its record sets.
+type ZoneExport struct {
+	ID     string           `json:"id"`
+	Name   string           `json:"name"`
+	RRSets []*domains.RRSet `json:"rrsets"`
+}
+
+// Export returns all managed zones with their record sets.
+func (p *Provider) Export(ctx context.Context) ([]*ZoneExport, error) {
+	client, err := p.getDomainsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.export(ctx, client)
+}
+
+// export returns all managed zones with their record sets using the given client.
+func (p *Provider) export(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+) ([]*ZoneExport, error) {
+	settings := p.settings.Load()
+
+	zones, err := settings.zoneFetcherClient.zones(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ZoneExport, 0, len(zones))
+	for _, zone := range zones {
+		rrSets, err := settings.rrSetFetcherClient.fetchRecords(ctx, client, zone.ID, map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &ZoneExport{
+			ID:     zone.ID,
+			Name:   zone.Name,
+			RRSets: rrSets,
+		})
+	}
+
+	return result, nil
+}
@@ -29,15 +29,9 @@ func findBestMatchingZone(rrSetName string, zones []*domains.Zone) (*domains.Zon
 	return domainZone, true
 }
 
-// findRRSet finds a record set by name and type in a list of record sets.
-func findRRSet(rrSetName, recordType string, rrSets []*domains.RRSet) (*domains.RRSet, bool) {
-	for _, rrSet := range rrSets {
-		if rrSet.Name == rrSetName && string(rrSet.Type) == recordType {
-			return rrSet, true
-		}
-	}
-
-	return nil, false
+// getRRSetKey returns the key a record set is identified by within the managed zones.
+func getRRSetKey(name, recordType string) string {
+	return strings.ToLower(provider.EnsureTrailingDot(name)) + " " + recordType
 }
 
 // modifyChange modifies a change to ensure it is valid for this provider.
@@ -183,24 +183,18 @@ func TestFindBestMatchingZone(t *testing.T) {
 	}
 }
 
-func TestFindRRSet(t *testing.T) {
+func TestGetRRSetKey(t *testing.T) {
 	t.Parallel()
 
-	rrSets := []*domains.RRSet{
-		{Name: "www.foo.com", Type: "A"},
-		{Name: "www.bar.com", Type: "A"},
-		{Name: "www.baz.com", Type: "A"},
-	}
-
 	tests := []struct {
 		name       string
 		rrSetName  string
 		recordType string
-		want       *domains.RRSet
-		wantFound  bool
+		want       string
 	}{
-		{"Matching RRSet", "www.foo.com", "A", rrSets[0], true},
-		{"No Matching RRSet", "www.test.com", "A", nil, false},
+		{"Trailing dot", "www.foo.com.", "A", "www.foo.com. A"},
+		{"Without trailing dot", "www.foo.com", "A", "www.foo.com. A"},
+		{"Upper case name", "WWW.Foo.com", "TXT", "www.foo.com. TXT"},
 	}
 
 	for _, tt := range tests {
@@ -208,9 +202,8 @@ func TestFindRRSet(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			got, found := findRRSet(tt.rrSetName, tt.recordType, rrSets)
-			if !reflect.DeepEqual(got, tt.want) || found != tt.wantFound {
-				t.Errorf("findRRSet() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			if got := getRRSetKey(tt.rrSetName, tt.recordType); got != tt.want {
+				t.Errorf("getRRSetKey() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,75 @@
+package selprovider
+
+import (
+	"context"
+	"fmt"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// Import reconciles the managed zones with the given endpoints. Missing record sets are created and record sets
+// with different targets or TTL are updated through ApplyChanges. Record sets not part of the endpoints are kept.
+// The changes are returned even if applying them fails.
+func (p *Provider) Import(ctx context.Context, endpoints []*endpoint.Endpoint) (*plan.Changes, error) {
+	client, err := p.getDomainsClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := p.settings.Load()
+
+	zones, err := settings.zoneFetcherClient.zones(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*domains.RRSet)
+	for _, zone := range zones {
+		rrSets, err := settings.rrSetFetcherClient.fetchRecords(ctx, client, zone.ID, map[string]string{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rrSet := range rrSets {
+			current[getRRSetKey(rrSet.Name, string(rrSet.Type))] = rrSet
+		}
+	}
+
+	changes := &plan.Changes{}
+	for _, ep := range endpoints {
+		settings.modifyChange(ep)
+
+		if _, found := findBestMatchingZone(ep.DNSName, zones); !found {
+			return nil, fmt.Errorf("no matching zone found for %s", ep.DNSName)
+		}
+
+		rrSet, found := current[getRRSetKey(ep.DNSName, ep.RecordType)]
+		switch {
+		case !found:
+			changes.Create = append(changes.Create, ep)
+		case !isRRSetEqual(rrSet, ep):
+			// the record set is looked up by its exact name on update
+			ep.DNSName = rrSet.Name
+			changes.UpdateOld = append(changes.UpdateOld, getRRSetEndpoint(rrSet))
+			changes.UpdateNew = append(changes.UpdateNew, ep)
+		}
+	}
+
+	if !changes.HasChanges() {
+		return changes, nil
+	}
+
+	return changes, p.applyChanges(ctx, client, changes)
+}
+
+// getRRSetEndpoint returns the endpoint of a record set.
+func getRRSetEndpoint(rrSet *domains.RRSet) *endpoint.Endpoint {
+	targets := make([]string, 0, len(rrSet.Records))
+	for _, record := range rrSet.Records {
+		targets = append(targets, record.Content)
+	}
+
+	return endpoint.NewEndpointWithTTL(rrSet.Name, string(rrSet.Type), endpoint.TTL(rrSet.TTL), targets...)
+}
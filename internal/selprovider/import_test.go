@@ -0,0 +1,147 @@
+package selprovider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestImport(t *testing.T) {
+	t.Parallel()
+
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("test.com.", "A", 300, "1.2.3.4"),
+		endpoint.NewEndpointWithTTL("test2.com.", "A", 300, "9.9.9.9"),
+		endpoint.NewEndpointWithTTL("new.test.com.", "A", 60, "1.1.1.1"),
+	}
+
+	t.Run("Creates and updates record sets", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := getImportServer(t)
+		defer server.Close()
+
+		dnsProvider, err := New(Config{
+			BaseURL:          server.URL,
+			KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+			DomainFilter:     endpoint.DomainFilter{},
+			Workers:          1,
+		}, zap.NewNop())
+		assert.NoError(t, err)
+
+		changes, err := dnsProvider.Import(context.Background(), endpoints)
+		assert.NoError(t, err)
+		assert.Len(t, changes.Create, 1)
+		assert.Len(t, changes.UpdateNew, 1)
+		assert.Empty(t, changes.Delete)
+		assert.ElementsMatch(t, []string{
+			"POST /zones/1234/rrset new.test.com.",
+			"PATCH /zones/5678/rrset/5678",
+		}, requests())
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := getImportServer(t)
+		defer server.Close()
+
+		dnsProvider, err := New(Config{
+			BaseURL:          server.URL,
+			KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+			DomainFilter:     endpoint.DomainFilter{},
+			DryRun:           true,
+			Workers:          1,
+		}, zap.NewNop())
+		assert.NoError(t, err)
+
+		_, err = dnsProvider.Import(context.Background(), endpoints)
+		assert.NoError(t, err)
+		assert.Empty(t, requests())
+
+		report, ok := dnsProvider.DryRunReport().(*DryRunReport)
+		assert.True(t, ok)
+		assert.Len(t, report.Zones, 2)
+	})
+
+	t.Run("No matching zone", func(t *testing.T) {
+		t.Parallel()
+
+		server, requests := getImportServer(t)
+		defer server.Close()
+
+		dnsProvider, err := New(Config{
+			BaseURL:          server.URL,
+			KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+			DomainFilter:     endpoint.DomainFilter{},
+			Workers:          1,
+		}, zap.NewNop())
+		assert.NoError(t, err)
+
+		_, err = dnsProvider.Import(context.Background(), []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("test.org.", "A", 300, "1.2.3.4"),
+		})
+		assert.Error(t, err)
+		assert.Empty(t, requests())
+	})
+}
+
+// getImportServer returns a server with the zones and record sets of getZonesResponseRecords and
+// getRrsetsResponseRecords recording every mutating request.
+func getImportServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []string
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	for _, zoneID := range []string{"1234", "5678"} {
+		zoneID := zoneID
+		mux.HandleFunc("/zones/"+zoneID+"/rrset", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet {
+				getRrsetsResponseRecords(t, w, zoneID)
+
+				return
+			}
+
+			var rrSet domains.RRSet
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&rrSet))
+
+			mu.Lock()
+			requests = append(requests, r.Method+" "+r.URL.Path+" "+rrSet.Name)
+			mu.Unlock()
+
+			rrSet.ID = "created"
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			assert.NoError(t, json.NewEncoder(w).Encode(rrSet))
+		})
+		mux.HandleFunc("/zones/"+zoneID+"/rrset/", func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			mu.Unlock()
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]string(nil), requests...)
+	}
+}
@@ -0,0 +1,154 @@
+package selprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// The leak tests are not parallel, so the goroutines of the parallel tests are paused and ignored by goleak.
+
+func TestRecordsStopsOnError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	server := getBlockingServer(t)
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          3,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = dnsProvider.Records(context.Background())
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	server.Close()
+}
+
+func TestRecordsStopsOnCancellation(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	server := getBlockingServer(t)
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.NewDomainFilter([]string{"blocking.com"}),
+		Workers:          3,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err = dnsProvider.Records(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	server.Close()
+}
+
+func TestStreamRecordsStopsOnEmitError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	server := getZonesFakeServer(t, "a.com", "b.com", "c.com", "d.com")
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          2,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	emitErr := errors.New("client gone")
+	emitted := 0
+	err = dnsProvider.StreamRecordsPerZone(context.Background(), func(endpoints []*endpoint.Endpoint) error {
+		emitted++
+
+		return emitErr
+	})
+	assert.ErrorIs(t, err, emitErr)
+	assert.Equal(t, 1, emitted)
+
+	server.Close()
+}
+
+func TestApplyChangesStopsOnError(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	server := getBlockingServer(t)
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          3,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("a.blocking.com", "A", "1.2.3.4"),
+			endpoint.NewEndpoint("b.blocking.com", "A", "1.2.3.4"),
+			endpoint.NewEndpoint("a.failing.com", "A", "1.2.3.4"),
+			endpoint.NewEndpoint("c.blocking.com", "A", "1.2.3.4"),
+			endpoint.NewEndpoint("d.blocking.com", "A", "1.2.3.4"),
+		},
+	}
+
+	start := time.Now()
+	err = dnsProvider.ApplyChanges(context.Background(), changes)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	server.Close()
+}
+
+// getBlockingServer returns a DNS API with the zones blocking.com and failing.com. Record set requests of
+// blocking.com block until the client cancels them, the ones of failing.com fail.
+func getBlockingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	zones := domains.List[domains.Zone]{
+		Count: 2,
+		Items: []*domains.Zone{
+			{ID: "blocking", Name: "blocking.com."},
+			{ID: "failing", Name: "failing.com."},
+		},
+	}
+	zonesResponse, err := json.Marshal(zones)
+	assert.NoError(t, err)
+
+	block := func(w http.ResponseWriter, r *http.Request) {
+		// the server notices the disconnect of the client only after the body is read
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}
+	fail := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/zones", responseHandler(zonesResponse, http.StatusOK))
+	mux.HandleFunc("/zones/blocking/rrset", block)
+	mux.HandleFunc("/zones/failing/rrset", fail)
+
+	return httptest.NewServer(mux)
+}
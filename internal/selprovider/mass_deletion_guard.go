@@ -0,0 +1,160 @@
+package selprovider
+
+import (
+	"context"
+	"fmt"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const blockedReasonMassDeletion = "mass_deletion"
+
+// MassDeletionGuardConfig configures the breaker that refuses batches of changes deleting too many records at once.
+// The breaker is disabled if neither MaxDeletions nor MaxDeletionPercent are set.
+type MassDeletionGuardConfig struct {
+	// MaxDeletions is the maximum number of records a single batch may delete.
+	MaxDeletions int
+	// MaxDeletionPercent is the maximum share of all records in the managed zones in percent a single batch may
+	// delete. Records not created by external-dns are counted as well.
+	MaxDeletionPercent float64
+	// Override lets batches exceeding the thresholds through.
+	Override bool
+}
+
+// enabled reports whether the breaker has to check batches at all.
+func (c MassDeletionGuardConfig) enabled() bool {
+	return c.MaxDeletions > 0 || c.MaxDeletionPercent > 0
+}
+
+// MassDeletionError is returned by ApplyChanges when the whole batch was refused because it deletes too many records.
+// The batch is refused again on every retry until the deletions are within the thresholds.
+type MassDeletionError struct {
+	Deletions []*endpoint.Endpoint
+	// Records is the number of records in the managed zones, it is only counted for a percentage threshold.
+	Records int
+	Reason  string
+}
+
+func (e *MassDeletionError) Error() string {
+	return fmt.Sprintf(
+		"mass deletion breaker refused the batch: %d of %d records would be deleted, %s",
+		len(e.Deletions), e.Records, e.Reason,
+	)
+}
+
+// RefusedChanges returns a human-readable description of every refused deletion.
+func (e *MassDeletionError) RefusedChanges() []string {
+	result := make([]string, 0, len(e.Deletions))
+	for _, change := range e.Deletions {
+		result = append(result, fmt.Sprintf("%s %s %s", DELETE, change.DNSName, change.RecordType))
+	}
+
+	return result
+}
+
+type massDeletionGuard struct {
+	config  MassDeletionGuardConfig
+	metrics metrics.ProviderMetrics
+	logger  *zap.Logger
+}
+
+func newMassDeletionGuard(
+	config MassDeletionGuardConfig,
+	metrics metrics.ProviderMetrics,
+	logger *zap.Logger,
+) *massDeletionGuard {
+	return &massDeletionGuard{
+		config:  config,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// check returns a *MassDeletionError if the deletions exceed one of the configured thresholds. The records are
+// only counted if a percentage threshold is configured.
+func (g *massDeletionGuard) check(
+	ctx context.Context,
+	deletions []*endpoint.Endpoint,
+	countRecords func(ctx context.Context) (int, error),
+) error {
+	if !g.config.enabled() {
+		return nil
+	}
+
+	if g.config.Override {
+		g.metrics.SetMassDeletionBreakerOpen(false)
+
+		return nil
+	}
+
+	records := 0
+	reason := ""
+
+	if g.config.MaxDeletions > 0 && len(deletions) > g.config.MaxDeletions {
+		reason = fmt.Sprintf("limit is %d records", g.config.MaxDeletions)
+	}
+
+	if reason == "" && g.config.MaxDeletionPercent > 0 && len(deletions) > 0 {
+		var err error
+		records, err = countRecords(ctx)
+		if err != nil {
+			return err
+		}
+
+		percent := float64(100)
+		if records > 0 {
+			percent = float64(len(deletions)) * 100 / float64(records)
+		}
+
+		if percent > g.config.MaxDeletionPercent {
+			reason = fmt.Sprintf("limit is %.2f%% of all records", g.config.MaxDeletionPercent)
+		}
+	}
+
+	if reason == "" {
+		g.metrics.SetMassDeletionBreakerOpen(false)
+
+		return nil
+	}
+
+	massDeletionErr := &MassDeletionError{
+		Deletions: deletions,
+		Records:   records,
+		Reason:    reason,
+	}
+
+	g.logger.Error(
+		"mass deletion breaker refused the batch",
+		zap.Error(massDeletionErr),
+		zap.Strings("deletions", massDeletionErr.RefusedChanges()),
+	)
+	g.metrics.SetMassDeletionBreakerOpen(true)
+	for range deletions {
+		g.metrics.CollectBlockedChange(DELETE, blockedReasonMassDeletion)
+	}
+
+	return massDeletionErr
+}
+
+// countRecords returns the number of records in all zones managed by the provider. Records are not told apart by
+// owner, so hand-made records count as well.
+func (p *Provider) countRecords(
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+) func(ctx context.Context) (int, error) {
+	return func(ctx context.Context) (int, error) {
+		endpointsPerZone, err := p.recordsPerZone(ctx, client)
+		if err != nil {
+			return 0, err
+		}
+
+		count := 0
+		for _, endpoints := range endpointsPerZone {
+			count += len(endpoints)
+		}
+
+		return count, nil
+	}
+}
@@ -0,0 +1,140 @@
+package selprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	mock_metrics "github.com/selectel/external-dns-selectel-webhook/pkg/metrics/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestMassDeletionGuard(t *testing.T) {
+	t.Parallel()
+
+	deleteBoth := []*endpoint.Endpoint{
+		{DNSName: "test.com", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: "A"},
+		{DNSName: "test2.com", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+	}
+
+	tests := []struct {
+		name            string
+		guard           MassDeletionGuardConfig
+		deletions       []*endpoint.Endpoint
+		expectedDeletes int32
+		expectRefused   bool
+		// expectedRecords is the number of records counted for a percentage threshold
+		expectedRecords int
+	}{
+		{
+			name:            "breaker disabled",
+			guard:           MassDeletionGuardConfig{},
+			deletions:       deleteBoth,
+			expectedDeletes: 2,
+		},
+		{
+			name:          "absolute limit exceeded",
+			guard:         MassDeletionGuardConfig{MaxDeletions: 1},
+			deletions:     deleteBoth,
+			expectRefused: true,
+		},
+		{
+			name:            "absolute limit not exceeded",
+			guard:           MassDeletionGuardConfig{MaxDeletions: 2},
+			deletions:       deleteBoth,
+			expectedDeletes: 2,
+		},
+		{
+			name:          "percentage exceeded",
+			guard:         MassDeletionGuardConfig{MaxDeletionPercent: 50},
+			deletions:     deleteBoth,
+			expectRefused: true,
+			// neither record has an ownership TXT record, they count anyway
+			expectedRecords: 2,
+		},
+		{
+			name:            "percentage not exceeded",
+			guard:           MassDeletionGuardConfig{MaxDeletionPercent: 50},
+			deletions:       deleteBoth[:1],
+			expectedDeletes: 1,
+		},
+		{
+			name:            "override set",
+			guard:           MassDeletionGuardConfig{MaxDeletions: 1, Override: true},
+			deletions:       deleteBoth,
+			expectedDeletes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deletes atomic.Int32
+			server := getMassDeletionGuardServer(t, &deletes)
+			defer server.Close()
+
+			ctrl := gomock.NewController(t)
+			providerMetrics := mock_metrics.NewMockProviderMetrics(ctrl)
+			providerMetrics.EXPECT().SetMassDeletionBreakerOpen(tt.expectRefused).MaxTimes(1)
+			if tt.expectRefused {
+				providerMetrics.EXPECT().CollectBlockedChange(DELETE, blockedReasonMassDeletion).Times(len(tt.deletions))
+			}
+
+			dnsProvider, err := New(Config{
+				BaseURL:           server.URL,
+				KeystoneProvider:  getDefaultKeystoneProvider(t, 1),
+				DomainFilter:      endpoint.DomainFilter{},
+				Workers:           1,
+				MassDeletionGuard: tt.guard,
+				Metrics:           providerMetrics,
+			}, zap.NewNop())
+			assert.NoError(t, err)
+
+			err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{Delete: tt.deletions})
+			assert.Equal(t, tt.expectedDeletes, deletes.Load())
+
+			var massDeletionErr *MassDeletionError
+			assert.Equal(t, tt.expectRefused, errors.As(err, &massDeletionErr))
+			if !tt.expectRefused {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.expectedRecords, massDeletionErr.Records)
+			}
+		})
+	}
+}
+
+func getMassDeletionGuardServer(t *testing.T, deletes *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	mux.HandleFunc("/zones/1234/rrset", func(w http.ResponseWriter, r *http.Request) {
+		getRrsetsResponseRecords(t, w, "1234")
+	})
+	mux.HandleFunc("/zones/5678/rrset", func(w http.ResponseWriter, r *http.Request) {
+		getRrsetsResponseRecords(t, w, "5678")
+	})
+	deleteHandler := func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		deletes.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+	mux.HandleFunc("/zones/1234/rrset/", deleteHandler)
+	mux.HandleFunc("/zones/5678/rrset/", deleteHandler)
+
+	return server
+}
@@ -10,6 +10,7 @@
 package mock_selprovider
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -40,16 +41,16 @@ func (m *MockKeystoneProvider) EXPECT() *MockKeystoneProviderMockRecorder {
 }
 
 // GetToken mocks base method.
-func (m *MockKeystoneProvider) GetToken() (string, error) {
+func (m *MockKeystoneProvider) GetToken(ctx context.Context) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetToken")
+	ret := m.ctrl.Call(m, "GetToken", ctx)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetToken indicates an expected call of GetToken.
-func (mr *MockKeystoneProviderMockRecorder) GetToken() *gomock.Call {
+func (mr *MockKeystoneProviderMockRecorder) GetToken(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockKeystoneProvider)(nil).GetToken))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetToken", reflect.TypeOf((*MockKeystoneProvider)(nil).GetToken), ctx)
 }
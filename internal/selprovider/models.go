@@ -1,6 +1,11 @@
 package selprovider
 
-import "sigs.k8s.io/external-dns/endpoint"
+import (
+	"sync/atomic"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
 
 const (
 	CREATE = "CREATE"
@@ -8,14 +13,21 @@ const (
 	DELETE = "DELETE"
 )
 
-// changeTask is a task that is passed to the worker.
+// changeTask is a single change of an ApplyChanges call.
 type changeTask struct {
 	change *endpoint.Endpoint
 	action string
 }
 
-// endpointError is a list of endpoints and an error to pass to workers.
-type endpointError struct {
-	endpoints []*endpoint.Endpoint
-	err       error
+// changeBatch holds the state shared by all changes of a single ApplyChanges call.
+type changeBatch struct {
+	// settings are the provider settings loaded at the start of the batch.
+	settings *settings
+	zones    []*domains.Zone
+	// rrSetIndex resolves the record sets of updates and deletions.
+	rrSetIndex *rrSetIndex
+	// dryRunDiff collects the changes that would be made, it is nil if dry run is disabled.
+	dryRunDiff *dryRunDiff
+	// undone counts the changes skipped because the provider is draining.
+	undone atomic.Int64
 }
@@ -0,0 +1,177 @@
+package selprovider
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+const blockedReasonNotOwned = "not_owned"
+
+// OwnershipGuardConfig configures the guard that protects record sets not managed by external-dns from
+// updates and deletions. The guard is disabled if neither AllowedNames nor RequireOwnershipTXT are set.
+type OwnershipGuardConfig struct {
+	// AllowedNames is a list of patterns for record set names that may always be updated or deleted.
+	AllowedNames []*regexp.Regexp
+	// RequireOwnershipTXT allows updates and deletions of record sets that have an external-dns ownership TXT record.
+	RequireOwnershipTXT bool
+	// OwnerID limits RequireOwnershipTXT to the ownership TXT records of the given external-dns owner.
+	OwnerID string
+	// TXTPrefix is the --txt-prefix external-dns is running with.
+	TXTPrefix string
+}
+
+// enabled reports whether the guard has to check changes at all.
+func (c OwnershipGuardConfig) enabled() bool {
+	return len(c.AllowedNames) > 0 || c.RequireOwnershipTXT
+}
+
+type ownershipGuard struct {
+	config  OwnershipGuardConfig
+	metrics metrics.ProviderMetrics
+	logger  *zap.Logger
+}
+
+func newOwnershipGuard(
+	config OwnershipGuardConfig,
+	metrics metrics.ProviderMetrics,
+	logger *zap.Logger,
+) *ownershipGuard {
+	return &ownershipGuard{
+		config:  config,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// filter splits the given endpoints into the ones that may be changed and the ones that are blocked.
+// Ownership TXT records are looked up once per zone before any change is made, so the order in which
+// external-dns deletes a record and its TXT record does not matter. The record sets are read from the index of
+// the batch, which resolves the changes afterwards without listing the zone again.
+func (g *ownershipGuard) filter(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	index *rrSetIndex,
+	endpoints []*endpoint.Endpoint,
+	zones []*domains.Zone,
+	action string,
+	ownedNames map[string]map[string]struct{},
+) ([]*endpoint.Endpoint, []*ChangeBlockedError, error) {
+	if !g.config.enabled() {
+		return endpoints, nil, nil
+	}
+
+	var allowed []*endpoint.Endpoint
+	var blocked []*ChangeBlockedError
+
+	for _, change := range endpoints {
+		name := strings.ToLower(provider.EnsureTrailingDot(change.DNSName))
+
+		if g.matchesAllowedNames(name) {
+			allowed = append(allowed, change)
+
+			continue
+		}
+
+		if g.config.RequireOwnershipTXT {
+			zone, found := findBestMatchingZone(name, zones)
+			if found {
+				owned, ok := ownedNames[zone.ID]
+				if !ok {
+					rrSets, err := index.rrSets(ctx, client, zone.ID)
+					if err != nil {
+						return nil, nil, err
+					}
+					owned = g.ownedNames(rrSets)
+					ownedNames[zone.ID] = owned
+				}
+
+				if g.isOwned(name, change.RecordType, owned) {
+					allowed = append(allowed, change)
+
+					continue
+				}
+			}
+		}
+
+		g.logger.Warn(
+			"change blocked by ownership guard",
+			zap.String("record", change.DNSName),
+			zap.String("type", change.RecordType),
+			zap.String("action", action),
+		)
+		g.metrics.CollectBlockedChange(action, blockedReasonNotOwned)
+
+		blocked = append(blocked, &ChangeBlockedError{
+			Action:     action,
+			DNSName:    change.DNSName,
+			RecordType: change.RecordType,
+			Reason:     "record is not owned by external-dns",
+		})
+	}
+
+	return allowed, blocked, nil
+}
+
+// matchesAllowedNames checks if the record set name matches one of the allowed name patterns.
+func (g *ownershipGuard) matchesAllowedNames(name string) bool {
+	for _, pattern := range g.config.AllowedNames {
+		if pattern.MatchString(name) || pattern.MatchString(strings.TrimSuffix(name, ".")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isOwned checks if one of the TXT record names external-dns uses for the given record set is owned.
+func (g *ownershipGuard) isOwned(name, recordType string, owned map[string]struct{}) bool {
+	candidates := []string{
+		g.config.TXTPrefix + name,
+		g.config.TXTPrefix + strings.ToLower(recordType) + "-" + name,
+	}
+
+	for _, candidate := range candidates {
+		if _, ok := owned[candidate]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownedNames returns the names of all TXT record sets of a zone that carry external-dns ownership labels.
+func (g *ownershipGuard) ownedNames(rrSets []*domains.RRSet) map[string]struct{} {
+	owned := make(map[string]struct{})
+	for _, rrSet := range rrSets {
+		if rrSet.Type != domains.TXT {
+			continue
+		}
+
+		for _, record := range rrSet.Records {
+			if g.isOwnershipRecord(record.Content) {
+				owned[strings.ToLower(provider.EnsureTrailingDot(rrSet.Name))] = struct{}{}
+
+				break
+			}
+		}
+	}
+
+	return owned
+}
+
+// isOwnershipRecord checks if the TXT content is an external-dns registry record of the configured owner.
+func (g *ownershipGuard) isOwnershipRecord(content string) bool {
+	labels, err := endpoint.NewLabelsFromStringPlain(content)
+	if err != nil {
+		return false
+	}
+
+	return g.config.OwnerID == "" || labels[endpoint.OwnerLabelKey] == g.config.OwnerID
+}
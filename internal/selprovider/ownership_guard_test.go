@@ -0,0 +1,173 @@
+package selprovider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	mock_metrics "github.com/selectel/external-dns-selectel-webhook/pkg/metrics/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestOwnershipGuard(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		guard           OwnershipGuardConfig
+		expectedDeletes int32
+		expectedBlocked []string
+	}{
+		{
+			name:            "guard disabled",
+			guard:           OwnershipGuardConfig{},
+			expectedDeletes: 2,
+		},
+		{
+			name:            "ownership TXT required",
+			guard:           OwnershipGuardConfig{RequireOwnershipTXT: true},
+			expectedDeletes: 1,
+			expectedBlocked: []string{"manual.test.com."},
+		},
+		{
+			name:            "ownership TXT of another owner",
+			guard:           OwnershipGuardConfig{RequireOwnershipTXT: true, OwnerID: "other"},
+			expectedDeletes: 0,
+			expectedBlocked: []string{"owned.test.com.", "manual.test.com."},
+		},
+		{
+			name: "allowed names",
+			guard: OwnershipGuardConfig{
+				RequireOwnershipTXT: true,
+				AllowedNames:        []*regexp.Regexp{regexp.MustCompile(`^manual\.`)},
+			},
+			expectedDeletes: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deletes atomic.Int32
+			server := getOwnershipGuardServer(t, &deletes)
+			defer server.Close()
+
+			ctrl := gomock.NewController(t)
+			providerMetrics := mock_metrics.NewMockProviderMetrics(ctrl)
+			providerMetrics.EXPECT().CollectBlockedChange(DELETE, blockedReasonNotOwned).Times(len(tt.expectedBlocked))
+
+			dnsProvider, err := New(Config{
+				BaseURL:          server.URL,
+				KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+				DomainFilter:     endpoint.DomainFilter{},
+				Workers:          1,
+				OwnershipGuard:   tt.guard,
+				Metrics:          providerMetrics,
+			}, zap.NewNop())
+			assert.NoError(t, err)
+
+			err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+				Delete: []*endpoint.Endpoint{
+					{DNSName: "owned.test.com.", Targets: endpoint.Targets{"1.2.3.4"}, RecordType: "A"},
+					{DNSName: "manual.test.com.", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+				},
+			})
+
+			assert.Equal(t, tt.expectedDeletes, deletes.Load())
+			if len(tt.expectedBlocked) == 0 {
+				assert.NoError(t, err)
+
+				return
+			}
+
+			var blockedErr *BlockedChangesError
+			assert.True(t, errors.As(err, &blockedErr))
+			blockedNames := make([]string, 0, len(blockedErr.Changes))
+			for _, change := range blockedErr.Changes {
+				blockedNames = append(blockedNames, change.DNSName)
+			}
+			assert.Equal(t, tt.expectedBlocked, blockedNames)
+		})
+	}
+}
+
+func TestOwnershipGuardWithoutMetrics(t *testing.T) {
+	t.Parallel()
+
+	var deletes atomic.Int32
+	server := getOwnershipGuardServer(t, &deletes)
+	defer server.Close()
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          1,
+		OwnershipGuard:   OwnershipGuardConfig{RequireOwnershipTXT: true},
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			{DNSName: "manual.test.com.", Targets: endpoint.Targets{"5.6.7.8"}, RecordType: "A"},
+		},
+	})
+
+	var blockedErr *BlockedChangesError
+	assert.True(t, errors.As(err, &blockedErr))
+	assert.Zero(t, deletes.Load())
+}
+
+func getOwnershipGuardServer(t *testing.T, deletes *atomic.Int32) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	mux.HandleFunc("/zones/1234/rrset", func(w http.ResponseWriter, r *http.Request) {
+		rrSets := domains.List[domains.RRSet]{
+			Count: 3,
+			Items: []*domains.RRSet{
+				{ID: "1", Name: "owned.test.com.", Type: "A", TTL: 300, Records: []domains.RecordItem{{Content: "1.2.3.4"}}},
+				{ID: "2", Name: "manual.test.com.", Type: "A", TTL: 300, Records: []domains.RecordItem{{Content: "5.6.7.8"}}},
+				{
+					ID:   "3",
+					Name: "a-owned.test.com.",
+					Type: "TXT",
+					TTL:  300,
+					Records: []domains.RecordItem{
+						{Content: `"heritage=external-dns,external-dns/owner=default,external-dns/resource=service/default/test"`},
+					},
+				},
+			},
+		}
+		response, err := json.Marshal(rrSets)
+		assert.NoError(t, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(response)
+	})
+	mux.HandleFunc("/zones/1234/rrset/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodDelete, r.Method)
+		deletes.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return server
+}
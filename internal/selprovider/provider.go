@@ -1,8 +1,15 @@
 package selprovider
 
 import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
 	domains "github.com/selectel/domains-go/pkg/v2"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/httpdefault"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/provider"
@@ -11,42 +18,148 @@ import (
 // Provider implements the DNS provider interface for Selectel DNS.
 type Provider struct {
 	provider.BaseProvider
+	keystoneProvider KeystoneProvider
+	endpoint         string
+	httpClient       *http.Client
+	logger           *zap.Logger
+	metrics          metrics.ProviderMetrics
+	settings         atomic.Pointer[settings]
+	auditSink        AuditSink
+	lastDryRunReport atomic.Pointer[DryRunReport]
+	drainer          *drainer
+	recordsCache     recordsCache
+	zoneRecords      zoneRecordsCache
+}
+
+// settings are the parts of the provider replaced by Reload. Every call loads them once, so a reload does not
+// affect the calls in flight.
+type settings struct {
 	domainFilter       endpoint.DomainFilter
-	keystoneProvider   KeystoneProvider
-	endpoint           string
 	dryRun             bool
 	workers            int
-	logger             *zap.Logger
+	recordsCacheTTL    time.Duration
+	fullResyncInterval time.Duration
+	zoneRecordsMaxAge  time.Duration
+	zoneTTLs           map[string]endpoint.TTL
 	zoneFetcherClient  *zoneFetcher
 	rrSetFetcherClient *rrSetFetcher
+	ownershipGuard     *ownershipGuard
+	massDeletionGuard  *massDeletionGuard
+}
+
+func newSettings(config Config, metrics metrics.ProviderMetrics, logger *zap.Logger) *settings {
+	rrSetFetcherClient := newRRSetFetcher(config.DomainFilter, logger)
+
+	zoneTTLs := make(map[string]endpoint.TTL, len(config.ZoneTTLs))
+	for zone, ttl := range config.ZoneTTLs {
+		zoneTTLs[strings.ToLower(provider.EnsureTrailingDot(zone))] = endpoint.TTL(ttl)
+	}
+
+	zoneRecordsMaxAge := config.ZoneRecordsMaxAge
+	if zoneRecordsMaxAge == 0 {
+		zoneRecordsMaxAge = config.FullResyncInterval
+	}
+
+	return &settings{
+		domainFilter:       config.DomainFilter,
+		dryRun:             config.DryRun,
+		workers:            config.Workers,
+		recordsCacheTTL:    config.RecordsCacheTTL,
+		fullResyncInterval: config.FullResyncInterval,
+		zoneRecordsMaxAge:  zoneRecordsMaxAge,
+		zoneTTLs:           zoneTTLs,
+		zoneFetcherClient:  newZoneFetcher(config.DomainFilter, config.ZoneFilter, config.Workers),
+		rrSetFetcherClient: rrSetFetcherClient,
+		ownershipGuard:     newOwnershipGuard(config.OwnershipGuard, metrics, logger),
+		massDeletionGuard:  newMassDeletionGuard(config.MassDeletionGuard, metrics, logger),
+	}
+}
+
+// modifyChange sets the TTL of the zone of the change if external-dns did not set one and ensures the change is
+// valid for this provider.
+func (s *settings) modifyChange(ep *endpoint.Endpoint) {
+	if ep.RecordTTL == 0 {
+		ep.RecordTTL = s.zoneTTL(ep.DNSName)
+	}
+
+	modifyChange(ep)
+}
+
+// zoneTTL returns the configured TTL of the most specific zone of the given name or 0 if there is none.
+func (s *settings) zoneTTL(name string) endpoint.TTL {
+	name = strings.ToLower(provider.EnsureTrailingDot(name))
+
+	var ttl endpoint.TTL
+	longest := 0
+	for zone, zoneTTL := range s.zoneTTLs {
+		if (name == zone || strings.HasSuffix(name, "."+zone)) && len(zone) > longest {
+			ttl, longest = zoneTTL, len(zone)
+		}
+	}
+
+	return ttl
 }
 
 // getDomainsClient returns v2.DNSClient with provided keystone and user-agent from httpdefault.UserAgent.
-func (p *Provider) getDomainsClient() (domains.DNSClient[domains.Zone, domains.RRSet], error) {
-	token, err := p.keystoneProvider.GetToken()
+// The token is requested within the given context.
+func (p *Provider) getDomainsClient(ctx context.Context) (domains.DNSClient[domains.Zone, domains.RRSet], error) {
+	token, err := p.keystoneProvider.GetToken(ctx)
 	if err != nil {
 		p.logger.Error("failed to get keystone token", zap.Error(err))
 
 		return nil, err
 	}
 
-	httpClient := httpdefault.Client()
+	return p.newDomainsClient(token), nil
+}
+
+// newDomainsClient returns v2.DNSClient authenticated with the given keystone token.
+func (p *Provider) newDomainsClient(token string) domains.DNSClient[domains.Zone, domains.RRSet] {
 	headers := httpdefault.Headers()
 	headers.Add("X-Auth-Token", token)
 
-	return domains.NewClient(p.endpoint, &httpClient, headers), nil
+	return domains.NewClient(p.endpoint, p.httpClient, headers)
 }
 
 // New creates a new Selectel DNS provider.
 func New(config Config, logger *zap.Logger) (*Provider, error) {
-	return &Provider{
-		domainFilter:       config.DomainFilter,
-		dryRun:             config.DryRun,
-		workers:            config.Workers,
-		logger:             logger,
-		keystoneProvider:   config.KeystoneProvider,
-		endpoint:           config.BaseURL,
-		zoneFetcherClient:  newZoneFetcher(config.DomainFilter),
-		rrSetFetcherClient: newRRSetFetcher(config.DomainFilter, logger),
-	}, nil
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		defaultClient := httpdefault.Client()
+		httpClient = &defaultClient
+	}
+
+	providerMetrics := config.Metrics
+	if providerMetrics == nil {
+		providerMetrics = nopProviderMetrics{}
+	}
+
+	p := &Provider{
+		logger:           logger,
+		metrics:          providerMetrics,
+		keystoneProvider: config.KeystoneProvider,
+		endpoint:         config.BaseURL,
+		httpClient:       httpClient,
+		auditSink:        config.AuditSink,
+		drainer:          newDrainer(),
+	}
+	p.settings.Store(newSettings(config, providerMetrics, logger))
+
+	return p, nil
 }
+
+// Reload replaces the filters, zone TTLs, workers, dry-run mode, records caching and safety guards by the ones of
+// the given config and drops the cached records. The API endpoint, HTTP client, Keystone provider, audit sink and
+// metrics are kept.
+func (p *Provider) Reload(config Config) {
+	p.settings.Store(newSettings(config, p.metrics, p.logger))
+	p.recordsCache.invalidate()
+	p.zoneRecords.reset()
+}
+
+// nopProviderMetrics is used if no metrics are configured, e.g. by the subcommands.
+type nopProviderMetrics struct{}
+
+func (nopProviderMetrics) CollectBlockedChange(string, string) {}
+
+func (nopProviderMetrics) SetMassDeletionBreakerOpen(bool) {}
@@ -0,0 +1,115 @@
+package selprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestSettingsModifyChange(t *testing.T) {
+	t.Parallel()
+
+	s := newSettings(Config{
+		ZoneTTLs: map[string]int{
+			"Example.com":     600,
+			"sub.example.com": 3600,
+		},
+	}, nil, zap.NewNop())
+
+	tests := []struct {
+		name     string
+		ep       *endpoint.Endpoint
+		expected endpoint.TTL
+	}{
+		{
+			name:     "Zone TTL",
+			ep:       &endpoint.Endpoint{DNSName: "www.example.com"},
+			expected: 600,
+		},
+		{
+			name:     "Zone apex",
+			ep:       &endpoint.Endpoint{DNSName: "example.com."},
+			expected: 600,
+		},
+		{
+			name:     "Most specific zone",
+			ep:       &endpoint.Endpoint{DNSName: "www.sub.example.com"},
+			expected: 3600,
+		},
+		{
+			name:     "TTL of external-dns",
+			ep:       &endpoint.Endpoint{DNSName: "www.example.com", RecordTTL: 60},
+			expected: 60,
+		},
+		{
+			name:     "Default TTL",
+			ep:       &endpoint.Endpoint{DNSName: "www.notexample.com"},
+			expected: 300,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s.modifyChange(tt.ep)
+			assert.Equal(t, tt.expected, tt.ep.RecordTTL)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/zones", func(w http.ResponseWriter, r *http.Request) {
+		getZonesResponseRecords(t, w)
+	})
+	mux.HandleFunc("/zones/1234/rrset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		getRrsetsResponseRecords(t, w, "1234")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request in dry run: %s %s", r.Method, r.URL.Path)
+	})
+
+	config := Config{
+		BaseURL:          server.URL,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 2),
+		DomainFilter:     endpoint.NewDomainFilter([]string{"test2.com"}),
+		DryRun:           true,
+		Workers:          1,
+	}
+	dnsProvider, err := New(config, zap.NewNop())
+	assert.NoError(t, err)
+
+	// a change in a zone outside of the domain filter fails
+	changes := &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "new.test.com", Targets: endpoint.Targets{"9.9.9.9"}, RecordType: "A"},
+		},
+	}
+	assert.Error(t, dnsProvider.ApplyChanges(context.Background(), changes))
+
+	config.DomainFilter = endpoint.NewDomainFilter([]string{"test.com"})
+	config.ZoneTTLs = map[string]int{"test.com": 900}
+	dnsProvider.Reload(config)
+
+	assert.Equal(t, []string{"test.com"}, dnsProvider.GetDomainFilter().(endpoint.DomainFilter).Filters)
+	assert.NoError(t, dnsProvider.ApplyChanges(context.Background(), changes))
+
+	report, ok := dnsProvider.DryRunReport().(*DryRunReport)
+	assert.True(t, ok)
+	assert.Len(t, report.Zones, 1)
+	assert.Equal(t, 900, report.Zones[0].Changes[0].Desired.TTL)
+}
@@ -2,109 +2,234 @@ package selprovider
 
 import (
 	"context"
+	"slices"
 
 	domains "github.com/selectel/domains-go/pkg/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 	"sigs.k8s.io/external-dns/endpoint"
 	"sigs.k8s.io/external-dns/provider"
 )
 
-// Records returns resource records.
+// Records returns resource records. Concurrent calls share a single fetch, its result is reused within the
+// records cache TTL.
 func (p *Provider) Records(ctx context.Context) ([]*endpoint.Endpoint, error) {
-	client, err := p.getDomainsClient()
+	endpointsPerZone, err := p.RecordsPerZone(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	zones, err := p.zoneFetcherClient.zones(ctx, client)
-	if err != nil {
-		return nil, err
-	}
+	return slices.Concat(endpointsPerZone...), nil
+}
 
-	var endpoints []*endpoint.Endpoint
-	endpointsErrorChannel := make(chan endpointError, len(zones))
-	zonesChan := make(chan string, len(zones))
+// RecordsPerZone returns the resource records like Records, but grouped by zone. The returned endpoints are shared
+// with concurrent calls and must not be modified.
+func (p *Provider) RecordsPerZone(ctx context.Context) ([][]*endpoint.Endpoint, error) {
+	settings := p.settings.Load()
 
-	for i := 0; i < p.workers; i++ {
-		go p.fetchRecordsWorker(ctx, client, zonesChan, endpointsErrorChannel)
-	}
+	endpointsPerZone, cached, err := p.recordsCache.get(ctx, settings.recordsCacheTTL,
+		func(ctx context.Context) ([][]*endpoint.Endpoint, error) {
+			client, err := p.getDomainsClient(ctx)
+			if err != nil {
+				return nil, err
+			}
 
-	for _, zone := range zones {
-		zonesChan <- zone.ID
+			return p.recordsPerZone(ctx, client)
+		},
+	)
+	if cached {
+		p.logger.Debug("records served from cache", zap.Int("zones", len(endpointsPerZone)))
 	}
 
-	for i := 0; i < len(zones); i++ {
-		endpointsErrorList := <-endpointsErrorChannel
-		if endpointsErrorList.err != nil {
-			close(zonesChan)
+	return endpointsPerZone, err
+}
+
+// StreamRecordsPerZone passes the resource records of every zone to emit in the order of the zones as soon as they
+// are read, so the records of all zones are not held in memory at once. An error of emit stops the stream and is
+// returned. With a records cache TTL the records are read like by RecordsPerZone, so concurrent calls share them.
+func (p *Provider) StreamRecordsPerZone(ctx context.Context, emit func(endpoints []*endpoint.Endpoint) error) error {
+	if p.settings.Load().recordsCacheTTL > 0 {
+		endpointsPerZone, err := p.RecordsPerZone(ctx)
+		if err != nil {
+			return err
+		}
 
-			return nil, endpointsErrorList.err
+		for _, endpoints := range endpointsPerZone {
+			if err := emit(endpoints); err != nil {
+				return err
+			}
 		}
-		endpoints = append(endpoints, endpointsErrorList.endpoints...)
+
+		return nil
 	}
 
-	close(zonesChan)
+	client, err := p.getDomainsClient(ctx)
+	if err != nil {
+		return err
+	}
 
-	return endpoints, nil
+	return p.streamRecordsPerZone(ctx, client, emit)
 }
 
-// fetchRecordsWorker fetches all records from a given zone.
-func (p *Provider) fetchRecordsWorker(
+// recordsPerZone returns resource records of all zones in the order of the zones using the given client.
+func (p *Provider) recordsPerZone(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
-	zonesChan chan string,
-	endpointsErrorChan chan<- endpointError,
-) {
-	for zoneID := range zonesChan {
-		p.processZoneRRSets(ctx, client, zoneID, endpointsErrorChan)
+) ([][]*endpoint.Endpoint, error) {
+	var endpointsPerZone [][]*endpoint.Endpoint
+	err := p.streamRecordsPerZone(ctx, client, func(endpoints []*endpoint.Endpoint) error {
+		endpointsPerZone = append(endpointsPerZone, endpoints)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	p.logger.Debug("fetch record set worker finished")
+	return endpointsPerZone, nil
 }
 
-// processZoneRRSets fetches and processes DNS records for a given zone.
-func (p *Provider) processZoneRRSets(
+// streamRecordsPerZone passes the resource records of all zones to emit in the order of the zones using the given
+// client. The workers read ahead of emit by at most one zone each. With a full resync interval, only the record sets
+// of the zones modified since the last scan or read longer than the maximum age ago are read and the other zones
+// are served from the cache.
+func (p *Provider) streamRecordsPerZone(
 	ctx context.Context,
 	client domains.DNSClient[domains.Zone, domains.RRSet],
-	zoneID string,
-	endpointsErrorChannel chan<- endpointError,
-) {
-	var endpoints []*endpoint.Endpoint
-	rrSets, err := p.rrSetFetcherClient.fetchRecords(ctx, client, zoneID, map[string]string{})
+	emit func(endpoints []*endpoint.Endpoint) error,
+) error {
+	settings := p.settings.Load()
+
+	zones, err := settings.zoneFetcherClient.zones(ctx, client)
 	if err != nil {
-		endpointsErrorChannel <- endpointError{
-			endpoints: nil,
-			err:       err,
+		return err
+	}
+
+	incremental := settings.fullResyncInterval > 0
+	var scan zoneScan
+	var recordsPerZone []*zoneRecords
+	if incremental {
+		scan = p.zoneRecords.startScan(settings.fullResyncInterval)
+		recordsPerZone = make([]*zoneRecords, len(zones))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the first failing zone cancels the requests of the other zones
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	// every zone is passed on its own channel, a slot is taken before the zone is read and freed once it was emitted
+	results := make([]chan *zoneRecords, len(zones))
+	for i := range results {
+		results[i] = make(chan *zoneRecords, 1)
+	}
+	slots := make(chan struct{}, max(1, settings.workers))
+
+	fetched := 0
+	group.Go(func() error {
+		for i, zone := range zones {
+			select {
+			case slots <- struct{}{}:
+			case <-groupCtx.Done():
+				return groupCtx.Err()
+			}
+
+			if incremental && !scan.full {
+				if cached, ok := p.zoneRecords.lookup(zone, settings.zoneRecordsMaxAge); ok {
+					results[i] <- cached
+
+					continue
+				}
+			}
+
+			fetched++
+			group.Go(func() error {
+				rrSets, err := settings.rrSetFetcherClient.fetchRecords(groupCtx, client, zone.ID, map[string]string{})
+				if err != nil {
+					return err
+				}
+
+				results[i] <- &zoneRecords{
+					updatedAt: zone.UpdatedAt,
+					readAt:    scan.startedAt,
+					endpoints: p.collectEndPoints(rrSets),
+				}
+
+				return nil
+			})
+		}
+
+		return nil
+	})
+
+	for i := range zones {
+		var records *zoneRecords
+		select {
+		case records = <-results[i]:
+		case <-groupCtx.Done():
+			if err := group.Wait(); err != nil {
+				return err
+			}
+
+			return ctx.Err()
 		}
+		<-slots
 
-		return
+		if incremental {
+			recordsPerZone[i] = records
+		}
+
+		if err := emit(records.endpoints); err != nil {
+			cancel()
+			_ = group.Wait()
+
+			return err
+		}
+	}
+
+	if err := group.Wait(); err != nil {
+		return err
 	}
 
-	endpoints = p.collectEndPoints(rrSets)
-	endpointsErrorChannel <- endpointError{
-		endpoints: endpoints,
-		err:       nil,
+	if incremental {
+		p.zoneRecords.store(scan, zones, recordsPerZone)
+		p.logger.Debug(
+			"zones scanned",
+			zap.Bool("full", scan.full),
+			zap.Int("zones", len(zones)),
+			zap.Int("fetched", fetched),
+		)
 	}
+
+	return nil
 }
 
-// collectEndPoints creates a list of Endpoints from the provided rrSets.
+// collectEndPoints creates a list of Endpoints from the provided rrSets. Each record set results in a single
+// endpoint with all its records as targets, like external-dns expects it.
 func (p *Provider) collectEndPoints(
 	rrSets []*domains.RRSet,
 ) []*endpoint.Endpoint {
 	var endpoints []*endpoint.Endpoint
 	for _, rrSet := range rrSets {
-		if provider.SupportedRecordType(string(rrSet.Type)) {
-			for _, rec := range rrSet.Records {
-				endpoints = append(
-					endpoints,
-					endpoint.NewEndpointWithTTL(
-						rrSet.Name,
-						string(rrSet.Type),
-						endpoint.TTL(rrSet.TTL),
-						rec.Content,
-					),
-				)
-			}
+		if !provider.SupportedRecordType(string(rrSet.Type)) || len(rrSet.Records) == 0 {
+			continue
 		}
+
+		targets := make([]string, 0, len(rrSet.Records))
+		for _, rec := range rrSet.Records {
+			targets = append(targets, rec.Content)
+		}
+
+		endpoints = append(
+			endpoints,
+			endpoint.NewEndpointWithTTL(
+				rrSet.Name,
+				string(rrSet.Type),
+				endpoint.TTL(rrSet.TTL),
+				targets...,
+			),
+		)
 	}
 
 	return endpoints
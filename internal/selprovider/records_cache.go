@@ -0,0 +1,118 @@
+package selprovider
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// recordsCache coalesces concurrent Records calls into a single fetch and reuses its result for the freshness
+// window. ApplyChanges does not read from it, so its lookups are never stale.
+type recordsCache struct {
+	mu     sync.Mutex
+	flight *recordsFlight
+
+	cached           bool
+	endpointsPerZone [][]*endpoint.Endpoint
+	fetchedAt        time.Time
+	// generation is increased by invalidate, so a fetch started before does not fill the cache
+	generation uint64
+}
+
+// recordsFlight is a running fetch shared by its waiters. It is canceled once all waiters gave up.
+type recordsFlight struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	endpointsPerZone [][]*endpoint.Endpoint
+	err              error
+}
+
+// get returns the cached endpoints if they were fetched within the freshness window, otherwise it waits for the
+// running fetch or starts a new one. The fetch keeps the values of the context of the first caller, but runs until
+// the last waiting caller is done.
+func (c *recordsCache) get(
+	ctx context.Context,
+	freshness time.Duration,
+	fetch func(ctx context.Context) ([][]*endpoint.Endpoint, error),
+) ([][]*endpoint.Endpoint, bool, error) {
+	c.mu.Lock()
+	if c.cached && freshness > 0 && time.Since(c.fetchedAt) < freshness {
+		endpointsPerZone := slices.Clone(c.endpointsPerZone)
+		c.mu.Unlock()
+
+		return endpointsPerZone, true, nil
+	}
+
+	f := c.flight
+	if f == nil {
+		f = c.start(ctx, fetch)
+	}
+	f.waiters++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return slices.Clone(f.endpointsPerZone), false, f.err
+	case <-ctx.Done():
+		c.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if c.flight == f {
+				c.flight = nil
+			}
+		}
+		c.mu.Unlock()
+
+		return nil, false, ctx.Err()
+	}
+}
+
+// start runs a new fetch. It must be called with the lock held.
+func (c *recordsCache) start(
+	ctx context.Context,
+	fetch func(ctx context.Context) ([][]*endpoint.Endpoint, error),
+) *recordsFlight {
+	fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	f := &recordsFlight{done: make(chan struct{}), cancel: cancel}
+	c.flight = f
+	generation := c.generation
+
+	go func() {
+		defer close(f.done)
+		defer cancel()
+
+		f.endpointsPerZone, f.err = fetch(fetchCtx)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.flight == f {
+			c.flight = nil
+		}
+		if f.err == nil && c.generation == generation {
+			c.cached = true
+			c.endpointsPerZone = f.endpointsPerZone
+			c.fetchedAt = time.Now()
+		}
+	}()
+
+	return f
+}
+
+// invalidate drops the cached endpoints. Later calls do not join the running fetch, since it may miss the changes
+// made before.
+func (c *recordsCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cached = false
+	c.endpointsPerZone = nil
+	c.flight = nil
+	c.generation++
+}
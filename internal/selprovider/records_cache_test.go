@@ -0,0 +1,191 @@
+package selprovider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestRecordsCacheCoalesces(t *testing.T) {
+	t.Parallel()
+
+	var cache recordsCache
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) ([][]*endpoint.Endpoint, error) {
+		fetches.Add(1)
+		<-release
+
+		return [][]*endpoint.Endpoint{{{DNSName: "www.example.com"}}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			endpoints, cached, err := cache.get(context.Background(), 0, fetch)
+			assert.NoError(t, err)
+			assert.False(t, cached)
+			assert.Len(t, endpoints, 1)
+		}()
+	}
+
+	// all callers wait for the first fetch
+	assert.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		return cache.flight != nil && cache.flight.waiters == 5
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), fetches.Load())
+
+	// without freshness window the result is not reused
+	_, cached, err := cache.get(context.Background(), 0, fetch)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestRecordsCacheFreshness(t *testing.T) {
+	t.Parallel()
+
+	var cache recordsCache
+	var fetches atomic.Int32
+	fetchErr := errors.New("failed")
+	fetch := func(ctx context.Context) ([][]*endpoint.Endpoint, error) {
+		if fetches.Add(1) == 1 {
+			return nil, fetchErr
+		}
+
+		return [][]*endpoint.Endpoint{{{DNSName: "www.example.com"}}}, nil
+	}
+
+	// errors are not cached
+	_, _, err := cache.get(context.Background(), time.Minute, fetch)
+	assert.ErrorIs(t, err, fetchErr)
+
+	_, cached, err := cache.get(context.Background(), time.Minute, fetch)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+
+	endpoints, cached, err := cache.get(context.Background(), time.Minute, fetch)
+	assert.NoError(t, err)
+	assert.True(t, cached)
+	assert.Len(t, endpoints, 1)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	cache.invalidate()
+	_, cached, err = cache.get(context.Background(), time.Minute, fetch)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, int32(3), fetches.Load())
+
+	// expired
+	_, cached, err = cache.get(context.Background(), time.Nanosecond, fetch)
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, int32(4), fetches.Load())
+}
+
+func TestRecordsCacheCancellation(t *testing.T) {
+	t.Parallel()
+
+	var cache recordsCache
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) ([][]*endpoint.Endpoint, error) {
+		close(started)
+		select {
+		case <-ctx.Done():
+			close(canceled)
+
+			return nil, ctx.Err()
+		case <-release:
+			return [][]*endpoint.Endpoint{{{DNSName: "www.example.com"}}}, nil
+		}
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstDone := make(chan error, 1)
+	go func() {
+		_, _, err := cache.get(firstCtx, 0, fetch)
+		firstDone <- err
+	}()
+	<-started
+
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	secondDone := make(chan error, 1)
+	go func() {
+		_, _, err := cache.get(secondCtx, 0, fetch)
+		secondDone <- err
+	}()
+	assert.Eventually(t, func() bool {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		return cache.flight.waiters == 2
+	}, time.Second, time.Millisecond)
+
+	// the fetch keeps running for the second caller
+	cancelFirst()
+	assert.ErrorIs(t, <-firstDone, context.Canceled)
+	select {
+	case <-canceled:
+		t.Fatal("fetch canceled while a caller is waiting")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// the fetch is canceled with the last caller
+	cancelSecond()
+	assert.ErrorIs(t, <-secondDone, context.Canceled)
+	<-canceled
+}
+
+func TestRecordsCacheInvalidatedByApplyChanges(t *testing.T) {
+	t.Parallel()
+
+	server := getZonesFakeServer(t, "example.com")
+	t.Cleanup(server.Close)
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 3),
+		DomainFilter:     endpoint.NewDomainFilter([]string{"example.com"}),
+		Workers:          1,
+		RecordsCacheTTL:  time.Minute,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	endpoints, err := dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, endpoints)
+
+	assert.NoError(t, dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "www.example.com", RecordType: "A", Targets: endpoint.Targets{"1.1.1.1"}},
+		},
+	}))
+
+	endpoints, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 1)
+
+	// served from the cache without a token
+	endpoints, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 1)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/goccy/go-json"
 	domains "github.com/selectel/domains-go/pkg/v2"
 	mock_selprovider "github.com/selectel/external-dns-selectel-webhook/internal/selprovider/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
@@ -39,6 +40,83 @@ func TestRecords(t *testing.T) {
 	assert.Equal(t, int64(300), int64(endpoints[1].RecordTTL))
 }
 
+func TestStreamRecordsPerZone(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	for i := range 5 {
+		zone, err := fake.AddZone(fmt.Sprintf("zone%d.com", i))
+		assert.NoError(t, err)
+		_, err = fake.AddRRSet(zone.ID, domains.RRSet{
+			Name:    "www." + zone.Name,
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "1.2.3.4"}},
+		})
+		assert.NoError(t, err)
+	}
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 2),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          3,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	// the zones are emitted one by one in the order of Records
+	var streamed []string
+	err = dnsProvider.StreamRecordsPerZone(context.Background(), func(endpoints []*endpoint.Endpoint) error {
+		assert.Len(t, endpoints, 1)
+		for _, ep := range endpoints {
+			streamed = append(streamed, ep.DNSName)
+		}
+
+		return nil
+	})
+	assert.NoError(t, err)
+
+	records, err := dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	expected := make([]string, 0, len(records))
+	for _, ep := range records {
+		expected = append(expected, ep.DNSName)
+	}
+	assert.Equal(t, expected, streamed)
+	assert.Len(t, streamed, 5)
+}
+
+func TestCollectEndPoints(t *testing.T) {
+	t.Parallel()
+
+	rrSets := []*domains.RRSet{
+		{
+			Name:    "multi.test.com.",
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "1.2.3.4"}, {Content: "5.6.7.8"}},
+		},
+		{Name: "empty.test.com.", Type: domains.A, TTL: 300},
+		{
+			Name:    "test.com.",
+			Type:    domains.SOA,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "a.ns.selectel.ru. support.selectel.ru. 1 10800 3600 604800 60"}},
+		},
+	}
+
+	// external-dns expects a single endpoint per name and type, empty and unsupported record sets are left out
+	endpoints := (&Provider{}).collectEndPoints(rrSets)
+	assert.Len(t, endpoints, 1)
+	assert.Equal(t, "multi.test.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.Targets{"1.2.3.4", "5.6.7.8"}, endpoints[0].Targets)
+	assert.Equal(t, endpoint.TTL(300), endpoints[0].RecordTTL)
+}
+
 // TestWrongJsonResponseRecords tests the scenario where the server returns a wrong JSON response.
 func TestWrongJsonResponseRecords(t *testing.T) {
 	t.Parallel()
@@ -164,7 +242,7 @@ func getDefaultKeystoneProvider(t *testing.T, callTimes int) KeystoneProvider {
 	t.Cleanup(ctrl.Finish)
 
 	p := mock_selprovider.NewMockKeystoneProvider(ctrl)
-	p.EXPECT().GetToken().Return("test", nil).Times(callTimes)
+	p.EXPECT().GetToken(gomock.Any()).Return("test", nil).Times(callTimes)
 
 	return p
 }
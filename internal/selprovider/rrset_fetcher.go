@@ -2,7 +2,6 @@ package selprovider
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 
 	domains "github.com/selectel/domains-go/pkg/v2"
@@ -53,37 +52,3 @@ func (r *rrSetFetcher) fetchRecords(
 
 	return rrSets, nil
 }
-
-// getRRSetForUpdateDeletion returns the record set to be deleted and the zone it belongs to.
-func (r *rrSetFetcher) getRRSetForUpdateDeletion(
-	ctx context.Context,
-	client domains.DNSClient[domains.Zone, domains.RRSet],
-	change *endpoint.Endpoint,
-	zones []*domains.Zone,
-) (*domains.Zone, *domains.RRSet, error) {
-	resultZone, found := findBestMatchingZone(change.DNSName, zones)
-	if !found {
-		r.logger.Error(
-			"record set name contains no zone dns name",
-			zap.String("name", change.DNSName),
-		)
-
-		return nil, nil, fmt.Errorf("record set name contains no zone dns name")
-	}
-
-	domainRrSets, err := r.fetchRecords(ctx, client, resultZone.ID, map[string]string{
-		domainsOptionName: change.DNSName,
-	})
-	if err != nil {
-		return nil, nil, err
-	}
-
-	resultRRSet, found := findRRSet(change.DNSName, change.RecordType, domainRrSets)
-	if !found {
-		r.logger.Info("record not found on record sets", zap.String("name", change.DNSName))
-
-		return nil, nil, fmt.Errorf("record not found on record sets")
-	}
-
-	return resultZone, resultRRSet, nil
-}
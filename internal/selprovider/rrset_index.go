@@ -0,0 +1,171 @@
+package selprovider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// rrSetIndex resolves the record sets of updates and deletions of a single ApplyChanges call. The record sets of
+// each affected zone are fetched once and indexed by name and type. A record set missing from the index is fetched
+// by its name again in case it was created after the zone was indexed.
+type rrSetIndex struct {
+	rrSetFetcher *rrSetFetcher
+	logger       *zap.Logger
+
+	mu    sync.Mutex
+	zones map[string]*zoneRRSetIndex
+}
+
+// zoneRRSetIndex is the index of a single zone. The zone is locked while it is fetched, so concurrent workers
+// fetch every zone only once.
+type zoneRRSetIndex struct {
+	mu     sync.Mutex
+	loaded bool
+	rrSets map[string]*domains.RRSet
+}
+
+func newRRSetIndex(rrSetFetcher *rrSetFetcher, logger *zap.Logger) *rrSetIndex {
+	return &rrSetIndex{
+		rrSetFetcher: rrSetFetcher,
+		logger:       logger,
+		zones:        make(map[string]*zoneRRSetIndex),
+	}
+}
+
+// getRRSetForUpdateDeletion returns the record set to be updated or deleted and the zone it belongs to.
+func (i *rrSetIndex) getRRSetForUpdateDeletion(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	change *endpoint.Endpoint,
+	zones []*domains.Zone,
+) (*domains.Zone, *domains.RRSet, error) {
+	resultZone, found := findBestMatchingZone(change.DNSName, zones)
+	if !found {
+		i.logger.Error(
+			"record set name contains no zone dns name",
+			zap.String("name", change.DNSName),
+		)
+
+		return nil, nil, fmt.Errorf("record set name contains no zone dns name")
+	}
+
+	resultRRSet, found, err := i.lookup(ctx, client, resultZone.ID, change.DNSName, change.RecordType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !found {
+		i.logger.Info("record not found on record sets", zap.String("name", change.DNSName))
+
+		return nil, nil, fmt.Errorf("record not found on record sets")
+	}
+
+	return resultZone, resultRRSet, nil
+}
+
+// lookup returns the record set of the zone with the given name and type.
+func (i *rrSetIndex) lookup(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	zoneID, name, recordType string,
+) (*domains.RRSet, bool, error) {
+	zone := i.zone(zoneID)
+
+	zone.mu.Lock()
+	defer zone.mu.Unlock()
+
+	err := i.load(ctx, client, zoneID, zone)
+	if err != nil {
+		return nil, false, err
+	}
+
+	key := getRRSetKey(name, recordType)
+	if rrSet, found := zone.rrSets[key]; found {
+		return rrSet, true, nil
+	}
+
+	i.logger.Debug("record set not found in index, fetching it by name", zap.String("name", name))
+
+	rrSets, err := i.rrSetFetcher.fetchRecords(ctx, client, zoneID, map[string]string{
+		domainsOptionName: name,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	zone.add(rrSets)
+	rrSet, found := zone.rrSets[key]
+
+	return rrSet, found, nil
+}
+
+// rrSets returns all indexed record sets of the zone.
+func (i *rrSetIndex) rrSets(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	zoneID string,
+) ([]*domains.RRSet, error) {
+	zone := i.zone(zoneID)
+
+	zone.mu.Lock()
+	defer zone.mu.Unlock()
+
+	err := i.load(ctx, client, zoneID, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*domains.RRSet, 0, len(zone.rrSets))
+	for _, rrSet := range zone.rrSets {
+		result = append(result, rrSet)
+	}
+
+	return result, nil
+}
+
+// load fetches the record sets of the zone unless they were fetched before. The zone has to be locked.
+func (i *rrSetIndex) load(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	zoneID string,
+	zone *zoneRRSetIndex,
+) error {
+	if zone.loaded {
+		return nil
+	}
+
+	rrSets, err := i.rrSetFetcher.fetchRecords(ctx, client, zoneID, map[string]string{})
+	if err != nil {
+		return err
+	}
+
+	zone.add(rrSets)
+	zone.loaded = true
+
+	return nil
+}
+
+// zone returns the index of the zone and creates it if it does not exist yet.
+func (i *rrSetIndex) zone(zoneID string) *zoneRRSetIndex {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	zone, found := i.zones[zoneID]
+	if !found {
+		zone = &zoneRRSetIndex{rrSets: make(map[string]*domains.RRSet)}
+		i.zones[zoneID] = zone
+	}
+
+	return zone
+}
+
+func (z *zoneRRSetIndex) add(rrSets []*domains.RRSet) {
+	for _, rrSet := range rrSets {
+		z.rrSets[getRRSetKey(rrSet.Name, string(rrSet.Type))] = rrSet
+	}
+}
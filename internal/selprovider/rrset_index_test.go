@@ -0,0 +1,199 @@
+package selprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	mock_selprovider "github.com/selectel/external-dns-selectel-webhook/internal/selprovider/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestRRSetIndex(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	counter := &listRRSetsCounter{handler: fake}
+	server := httptest.NewServer(counter)
+	defer server.Close()
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+	for _, name := range []string{"a.example.com.", "b.example.com."} {
+		_, err = fake.AddRRSet(zone.ID, domains.RRSet{
+			Name:    name,
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "1.2.3.4"}},
+		})
+		assert.NoError(t, err)
+	}
+
+	client := getFakeServerClient(t, server)
+	index := newRRSetIndex(newRRSetFetcher(endpoint.DomainFilter{}, zap.NewNop()), zap.NewNop())
+	zones := []*domains.Zone{zone}
+
+	for _, name := range []string{"a.example.com.", "B.example.com.", "a.example.com."} {
+		resultZone, rrSet, err := index.getRRSetForUpdateDeletion(
+			context.Background(), client, &endpoint.Endpoint{DNSName: name, RecordType: "A"}, zones,
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, zone.ID, resultZone.ID)
+		assert.Equal(t, strings.ToLower(name), rrSet.Name)
+	}
+	assert.Equal(t, int64(1), counter.list.Load())
+	assert.Equal(t, int64(0), counter.listByName.Load())
+
+	// record sets created after the zone was indexed are fetched by name
+	_, err = fake.AddRRSet(zone.ID, domains.RRSet{
+		Name:    "late.example.com.",
+		Type:    domains.A,
+		TTL:     300,
+		Records: []domains.RecordItem{{Content: "1.2.3.4"}},
+	})
+	assert.NoError(t, err)
+
+	_, rrSet, err := index.getRRSetForUpdateDeletion(
+		context.Background(), client, &endpoint.Endpoint{DNSName: "late.example.com.", RecordType: "A"}, zones,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "late.example.com.", rrSet.Name)
+	assert.Equal(t, int64(1), counter.listByName.Load())
+
+	_, _, err = index.getRRSetForUpdateDeletion(
+		context.Background(), client, &endpoint.Endpoint{DNSName: "a.example.com.", RecordType: "TXT"}, zones,
+	)
+	assert.Error(t, err)
+
+	_, _, err = index.getRRSetForUpdateDeletion(
+		context.Background(), client, &endpoint.Endpoint{DNSName: "a.example.org.", RecordType: "A"}, zones,
+	)
+	assert.Error(t, err)
+
+	assert.Equal(t, int64(1), counter.list.Load())
+}
+
+func TestRRSetIndexSharedWithOwnershipGuard(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	counter := &listRRSetsCounter{handler: fake}
+	server := httptest.NewServer(counter)
+	defer server.Close()
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+	rrSets := []domains.RRSet{
+		{Name: "www.example.com.", Type: domains.A, Records: []domains.RecordItem{{Content: "1.2.3.4"}}},
+		{
+			Name:    "a-www.example.com.",
+			Type:    domains.TXT,
+			Records: []domains.RecordItem{{Content: `"heritage=external-dns,external-dns/owner=default"`}},
+		},
+	}
+	for _, rrSet := range rrSets {
+		rrSet.TTL = 300
+		_, err = fake.AddRRSet(zone.ID, rrSet)
+		assert.NoError(t, err)
+	}
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          1,
+		OwnershipGuard:   OwnershipGuardConfig{RequireOwnershipTXT: true},
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Delete: []*endpoint.Endpoint{
+			{DNSName: "www.example.com", RecordType: "A", Targets: endpoint.Targets{"1.2.3.4"}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, fake.RRSets(zone.ID), 1)
+
+	// the guard and the deletion use the same listing of the zone
+	assert.Equal(t, int64(1), counter.list.Load())
+	assert.Equal(t, int64(0), counter.listByName.Load())
+}
+
+func BenchmarkApplyChangesUpdates(b *testing.B) {
+	const recordSets = 500
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	counter := &listRRSetsCounter{handler: fake}
+	server := httptest.NewServer(counter)
+	defer server.Close()
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(b, err)
+
+	updates := make([]*endpoint.Endpoint, 0, recordSets)
+	for i := 0; i < recordSets; i++ {
+		name := fmt.Sprintf("record-%d.example.com.", i)
+		_, err = fake.AddRRSet(zone.ID, domains.RRSet{
+			Name:    name,
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "1.2.3.4"}},
+		})
+		assert.NoError(b, err)
+
+		updates = append(updates, endpoint.NewEndpointWithTTL(name, "A", 600, "5.6.7.8"))
+	}
+
+	ctrl := gomock.NewController(b)
+	keystoneProvider := mock_selprovider.NewMockKeystoneProvider(ctrl)
+	keystoneProvider.EXPECT().GetToken(gomock.Any()).Return("test", nil).AnyTimes()
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: keystoneProvider,
+		DomainFilter:     endpoint.DomainFilter{},
+		Workers:          10,
+	}, zap.NewNop())
+	assert.NoError(b, err)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err = dnsProvider.ApplyChanges(context.Background(), &plan.Changes{UpdateNew: updates})
+		assert.NoError(b, err)
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(counter.list.Load()+counter.listByName.Load())/float64(b.N), "list-requests/op")
+}
+
+// listRRSetsCounter counts the requests listing the record sets of a zone.
+type listRRSetsCounter struct {
+	handler    http.Handler
+	list       atomic.Int64
+	listByName atomic.Int64
+}
+
+func (c *listRRSetsCounter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/rrset") {
+		if r.URL.Query().Has(domainsOptionName) {
+			c.listByName.Add(1)
+		} else {
+			c.list.Add(1)
+		}
+	}
+
+	c.handler.ServeHTTP(w, r)
+}
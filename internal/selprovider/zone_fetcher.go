@@ -2,48 +2,172 @@ package selprovider
 
 import (
 	"context"
+	"slices"
 	"strconv"
+	"strings"
 
 	domains "github.com/selectel/domains-go/pkg/v2"
+	"golang.org/x/sync/errgroup"
 	"sigs.k8s.io/external-dns/endpoint"
 )
 
+// ZoneFilterConfig selects the managed zones by their id and state in addition to the domain filter.
+type ZoneFilterConfig struct {
+	// ZoneIDs are the exact ids of the managed zones. Zones are not filtered by id if it is empty.
+	ZoneIDs []string
+	// SkipDisabled skips zones that are disabled.
+	SkipDisabled bool
+	// SkipUndelegated skips zones whose last delegation check failed.
+	SkipUndelegated bool
+}
+
+// match checks if the zone is selected by the filter.
+func (c ZoneFilterConfig) match(zone *domains.Zone) bool {
+	if c.SkipDisabled && zone.Disabled {
+		return false
+	}
+
+	if c.SkipUndelegated && !zone.LastCheckStatus {
+		return false
+	}
+
+	return len(c.ZoneIDs) == 0 || slices.Contains(c.ZoneIDs, zone.ID)
+}
+
 type zoneFetcher struct {
 	domainFilter endpoint.DomainFilter
+	zoneFilter   ZoneFilterConfig
+	workers      int
 }
 
 func newZoneFetcher(
 	domainFilter endpoint.DomainFilter,
+	zoneFilter ZoneFilterConfig,
+	workers int,
 ) *zoneFetcher {
 	return &zoneFetcher{
 		domainFilter: domainFilter,
+		zoneFilter:   zoneFilter,
+		workers:      workers,
 	}
 }
 
-// zones returns filtered list of v2.Zone if domainFilter is set.
+// zones returns filtered list of v2.Zone if domainFilter is set. The include filters are passed to the API as
+// filter option to reduce the number of fetched zones since every zone they match contains them. The complete
+// domainFilter including exclusions and regular expressions is applied to the zone names afterward.
 func (z *zoneFetcher) zones(ctx context.Context, client domains.DNSClient[domains.Zone, domains.RRSet]) ([]*domains.Zone, error) {
-	if len(z.domainFilter.Filters) == 0 {
+	filters := collapseFilters(z.domainFilter.Filters)
+	if len(filters) == 0 {
 		zones, err := z.fetchZones(ctx, client, map[string]string{})
 		if err != nil {
 			return nil, err
 		}
 
-		return zones, nil
+		return z.filterZones(zones), nil
 	}
 
-	var result []*domains.Zone
-	// send one request per filter
-	for _, filter := range z.domainFilter.Filters {
-		zones, err := z.fetchZones(ctx, client, map[string]string{
-			domainsOptionFilter: filter,
+	zones, err := z.fetchZonesWithWorkers(ctx, client, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return z.filterZones(uniqueZones(zones)), nil
+}
+
+// fetchZonesWithWorkers sends one request per filter with workers and returns the zones in the order of the filters.
+// The first failing request cancels the other ones.
+func (z *zoneFetcher) fetchZonesWithWorkers(
+	ctx context.Context,
+	client domains.DNSClient[domains.Zone, domains.RRSet],
+	filters []string,
+) ([]*domains.Zone, error) {
+	zonesPerFilter := make([][]*domains.Zone, len(filters))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(max(1, z.workers))
+
+	for i, filter := range filters {
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
+
+			zones, err := z.fetchZones(groupCtx, client, map[string]string{
+				domainsOptionFilter: filter,
+			})
+			if err != nil {
+				return err
+			}
+
+			zonesPerFilter[i] = zones
+
+			return nil
 		})
-		if err != nil {
-			return nil, err
+	}
+
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	return slices.Concat(zonesPerFilter...), nil
+}
+
+// collapseFilters returns the filters without duplicates and without filters that contain another filter. The API
+// matches the filter option as a substring of the zone name, so every zone found by such a filter is also found by
+// the filter it contains. An empty filter matches all zones, so no filters are returned then.
+func collapseFilters(filters []string) []string {
+	result := make([]string, 0, len(filters))
+	for i, filter := range filters {
+		if filter == "" {
+			return nil
+		}
+
+		redundant := false
+		for j, other := range filters {
+			if i == j {
+				continue
+			}
+			// keep the first one of equal filters
+			if (filter == other && j < i) || (filter != other && strings.Contains(filter, other)) {
+				redundant = true
+
+				break
+			}
+		}
+
+		if !redundant {
+			result = append(result, filter)
+		}
+	}
+
+	return result
+}
+
+// uniqueZones returns the zones without duplicates by zone id keeping the first occurrence.
+func uniqueZones(zones []*domains.Zone) []*domains.Zone {
+	seen := make(map[string]struct{}, len(zones))
+	result := make([]*domains.Zone, 0, len(zones))
+	for _, zone := range zones {
+		if _, found := seen[zone.ID]; found {
+			continue
+		}
+		seen[zone.ID] = struct{}{}
+		result = append(result, zone)
+	}
+
+	return result
+}
+
+// filterZones returns the zones whose names match the domainFilter and that are selected by the zoneFilter.
+func (z *zoneFetcher) filterZones(zones []*domains.Zone) []*domains.Zone {
+	result := make([]*domains.Zone, 0, len(zones))
+	for _, zone := range zones {
+		if z.domainFilter.Match(zone.Name) && z.zoneFilter.match(zone) {
+			result = append(result, zone)
 		}
-		result = append(result, zones...)
 	}
 
-	return result, nil
+	return result
 }
 
 // fetchZones fetches all []v2.Zone from Selectel DNS API. It may be filtered with options["filter"] provided.
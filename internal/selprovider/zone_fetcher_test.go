@@ -0,0 +1,257 @@
+package selprovider
+
+import (
+	"context"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestZonesDomainFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		domainFilter  endpoint.DomainFilter
+		expectedZones []string
+	}{
+		{
+			name:          "No filter",
+			domainFilter:  endpoint.DomainFilter{},
+			expectedZones: []string{"example.com.", "example.org.", "other.net.", "sub.example.com."},
+		},
+		{
+			name:          "Include",
+			domainFilter:  endpoint.NewDomainFilter([]string{"Example.com."}),
+			expectedZones: []string{"example.com.", "sub.example.com."},
+		},
+		{
+			name:          "Include subdomains only",
+			domainFilter:  endpoint.NewDomainFilter([]string{".example.com"}),
+			expectedZones: []string{"sub.example.com."},
+		},
+		{
+			name:          "Include and exclude",
+			domainFilter:  endpoint.NewDomainFilterWithExclusions([]string{"example.com"}, []string{"sub.example.com"}),
+			expectedZones: []string{"example.com."},
+		},
+		{
+			name:          "Exclude only",
+			domainFilter:  endpoint.NewDomainFilterWithExclusions(nil, []string{"example.com"}),
+			expectedZones: []string{"example.org.", "other.net."},
+		},
+		{
+			name:          "Regex",
+			domainFilter:  endpoint.NewRegexDomainFilter(regexp.MustCompile(`^example\.`), nil),
+			expectedZones: []string{"example.com.", "example.org."},
+		},
+		{
+			name:          "Regex exclusion",
+			domainFilter:  endpoint.NewRegexDomainFilter(nil, regexp.MustCompile(`example`)),
+			expectedZones: []string{"other.net."},
+		},
+		{
+			name:          "Overlapping filters",
+			domainFilter:  endpoint.NewDomainFilter([]string{"example.com", "sub.example.com", "example.org"}),
+			expectedZones: []string{"example.com.", "example.org.", "sub.example.com."},
+		},
+		{
+			name:          "Overlapping filters in reverse order",
+			domainFilter:  endpoint.NewDomainFilter([]string{"sub.example.com", "example.com"}),
+			expectedZones: []string{"example.com.", "sub.example.com."},
+		},
+		{
+			name:          "Duplicate filters",
+			domainFilter:  endpoint.NewDomainFilter([]string{"example.org", "Example.org."}),
+			expectedZones: []string{"example.org."},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := getZonesFakeServer(t, "example.com", "sub.example.com", "example.org", "other.net")
+			defer server.Close()
+
+			dnsProvider, err := New(Config{
+				BaseURL:          server.URL + fakeselectel.DomainsPath,
+				KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+				DomainFilter:     tt.domainFilter,
+				Workers:          3,
+			}, zap.NewNop())
+			assert.NoError(t, err)
+
+			client, err := dnsProvider.getDomainsClient(context.Background())
+			assert.NoError(t, err)
+
+			zones, err := dnsProvider.settings.Load().zoneFetcherClient.zones(context.Background(), client)
+			assert.NoError(t, err)
+
+			names := make([]string, 0, len(zones))
+			for _, zone := range zones {
+				names = append(names, zone.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedZones, names)
+		})
+	}
+}
+
+func TestCollapseFilters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		filters  []string
+		expected []string
+	}{
+		{
+			name:     "No filters",
+			filters:  nil,
+			expected: []string{},
+		},
+		{
+			name:     "Distinct filters",
+			filters:  []string{"example.com", "example.org"},
+			expected: []string{"example.com", "example.org"},
+		},
+		{
+			name:     "Subdomain filter",
+			filters:  []string{"sub.example.com", "example.com", "a.sub.example.com"},
+			expected: []string{"example.com"},
+		},
+		{
+			name:     "Duplicate filters",
+			filters:  []string{"example.com", "example.org", "example.com"},
+			expected: []string{"example.com", "example.org"},
+		},
+		{
+			name:     "Empty filter",
+			filters:  []string{"example.com", ""},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.expected, collapseFilters(tt.filters))
+		})
+	}
+}
+
+// getZonesFakeServer returns a fake Selectel DNS API with the given zones accepting the token of
+// getDefaultKeystoneProvider.
+func getZonesFakeServer(t *testing.T, zones ...string) *httptest.Server {
+	t.Helper()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+
+	for _, zone := range zones {
+		_, err := fake.AddZone(zone)
+		assert.NoError(t, err)
+	}
+
+	return httptest.NewServer(fake)
+}
+
+func TestZonesZoneFilter(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	zones := make(map[string]string)
+	for _, name := range []string{"active.com", "disabled.com", "undelegated.com"} {
+		zone, err := fake.AddZone(name)
+		assert.NoError(t, err)
+		zones[name] = zone.ID
+
+		assert.NoError(t, fake.SetZoneDelegated(zone.ID, name != "undelegated.com"))
+	}
+
+	client := getFakeServerClient(t, server)
+	assert.NoError(t, client.UpdateZoneState(context.Background(), zones["disabled.com"], true))
+
+	tests := []struct {
+		name          string
+		zoneFilter    ZoneFilterConfig
+		expectedZones []string
+	}{
+		{
+			name:          "No filter",
+			expectedZones: []string{"active.com.", "disabled.com.", "undelegated.com."},
+		},
+		{
+			name:          "Zone ids",
+			zoneFilter:    ZoneFilterConfig{ZoneIDs: []string{zones["disabled.com"], zones["undelegated.com"]}},
+			expectedZones: []string{"disabled.com.", "undelegated.com."},
+		},
+		{
+			name:          "Zone id suffix does not match",
+			zoneFilter:    ZoneFilterConfig{ZoneIDs: []string{zones["active.com"][len(zones["active.com"])-1:]}},
+			expectedZones: []string{},
+		},
+		{
+			name:          "Skip disabled",
+			zoneFilter:    ZoneFilterConfig{SkipDisabled: true},
+			expectedZones: []string{"active.com.", "undelegated.com."},
+		},
+		{
+			name:          "Skip undelegated",
+			zoneFilter:    ZoneFilterConfig{SkipUndelegated: true},
+			expectedZones: []string{"active.com.", "disabled.com."},
+		},
+		{
+			name:          "Combined",
+			zoneFilter:    ZoneFilterConfig{ZoneIDs: []string{zones["disabled.com"]}, SkipDisabled: true},
+			expectedZones: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fetcher := newZoneFetcher(endpoint.DomainFilter{}, tt.zoneFilter, 1)
+
+			result, err := fetcher.zones(context.Background(), getFakeServerClient(t, server))
+			assert.NoError(t, err)
+
+			names := make([]string, 0, len(result))
+			for _, zone := range result {
+				names = append(names, zone.Name)
+			}
+			assert.ElementsMatch(t, tt.expectedZones, names)
+		})
+	}
+}
+
+// getFakeServerClient returns a client for the fake Selectel DNS API using the token of getDefaultKeystoneProvider.
+func getFakeServerClient(t *testing.T, server *httptest.Server) domains.DNSClient[domains.Zone, domains.RRSet] {
+	t.Helper()
+
+	dnsProvider, err := New(Config{
+		BaseURL:          server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: getDefaultKeystoneProvider(t, 1),
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	client, err := dnsProvider.getDomainsClient(context.Background())
+	assert.NoError(t, err)
+
+	return client
+}
@@ -0,0 +1,103 @@
+package selprovider
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// WriteZoneFile writes the zones in the RFC 1035 master file format. All names are written fully qualified.
+func WriteZoneFile(w io.Writer, zones []*ZoneExport) error {
+	for i, zone := range zones {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "; zone %s\n$ORIGIN %s\n", zone.ID, provider.EnsureTrailingDot(zone.Name))
+		if err != nil {
+			return err
+		}
+
+		for _, rrSet := range zone.RRSets {
+			for _, record := range rrSet.Records {
+				_, err = fmt.Fprintf(
+					w, "%s\t%d\tIN\t%s\t%s\n",
+					provider.EnsureTrailingDot(rrSet.Name), rrSet.TTL, rrSet.Type, record.Content,
+				)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseZoneFile reads a RFC 1035 master file and returns one endpoint per name and type. Relative names are
+// resolved against origin unless the file sets its own $ORIGIN. SOA records are skipped since they are managed
+// by Selectel.
+func ParseZoneFile(r io.Reader, origin string) ([]*endpoint.Endpoint, error) {
+	parser := dns.NewZoneParser(r, dns.Fqdn(origin), "")
+
+	var result []*endpoint.Endpoint
+	endpoints := make(map[string]*endpoint.Endpoint)
+
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		header := rr.Header()
+		if header.Rrtype == dns.TypeSOA {
+			continue
+		}
+
+		recordType := dns.TypeToString[header.Rrtype]
+		name := strings.ToLower(header.Name)
+		content := strings.TrimPrefix(rr.String(), header.String())
+
+		key := name + " " + recordType
+		ep, found := endpoints[key]
+		if !found {
+			ep = endpoint.NewEndpointWithTTL(name, recordType, endpoint.TTL(header.Ttl))
+			endpoints[key] = ep
+			result = append(result, ep)
+		}
+		ep.Targets = append(ep.Targets, content)
+	}
+
+	if err := parser.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse zone file: %w", err)
+	}
+
+	return result, nil
+}
+
+// isRRSetEqual checks if the record set already has the TTL and the targets of the endpoint.
+func isRRSetEqual(rrSet *domains.RRSet, ep *endpoint.Endpoint) bool {
+	if rrSet.TTL != int(ep.RecordTTL) || len(rrSet.Records) != len(ep.Targets) {
+		return false
+	}
+
+	current := make([]string, 0, len(rrSet.Records))
+	for _, record := range rrSet.Records {
+		current = append(current, record.Content)
+	}
+
+	desired := append([]string(nil), ep.Targets...)
+	sort.Strings(current)
+	sort.Strings(desired)
+
+	for i := range current {
+		if current[i] != desired[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,113 @@
+package selprovider
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestWriteZoneFile(t *testing.T) {
+	t.Parallel()
+
+	zones := []*ZoneExport{
+		{
+			ID:   "1234",
+			Name: "test.com.",
+			RRSets: []*domains.RRSet{
+				{
+					Name:    "test.com.",
+					Type:    "A",
+					TTL:     300,
+					Records: []domains.RecordItem{{Content: "1.2.3.4"}, {Content: "5.6.7.8"}},
+				},
+				{
+					Name:    "www.test.com.",
+					Type:    "CNAME",
+					TTL:     60,
+					Records: []domains.RecordItem{{Content: "test.com."}},
+				},
+				{
+					Name:    "test.com.",
+					Type:    "TXT",
+					TTL:     300,
+					Records: []domains.RecordItem{{Content: "\"heritage=external-dns\""}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteZoneFile(&buf, zones)
+	assert.NoError(t, err)
+
+	endpoints, err := ParseZoneFile(&buf, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		{
+			DNSName:    "test.com",
+			RecordType: "A",
+			RecordTTL:  300,
+			Targets:    endpoint.Targets{"1.2.3.4", "5.6.7.8"},
+			Labels:     endpoint.Labels{},
+		},
+		{
+			DNSName:    "www.test.com",
+			RecordType: "CNAME",
+			RecordTTL:  60,
+			Targets:    endpoint.Targets{"test.com."},
+			Labels:     endpoint.Labels{},
+		},
+		{
+			DNSName:    "test.com",
+			RecordType: "TXT",
+			RecordTTL:  300,
+			Targets:    endpoint.Targets{"\"heritage=external-dns\""},
+			Labels:     endpoint.Labels{},
+		},
+	}, endpoints)
+}
+
+func TestParseZoneFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Relative names and SOA", func(t *testing.T) {
+		t.Parallel()
+
+		zoneFile := strings.Join([]string{
+			"$TTL 120",
+			"@ IN SOA ns1.selectel.org. support.selectel.ru. 1 10800 3600 604800 60",
+			"@ IN MX 10 mail",
+			"Mail 300 IN A 1.2.3.4",
+		}, "\n")
+
+		endpoints, err := ParseZoneFile(strings.NewReader(zoneFile), "test.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []*endpoint.Endpoint{
+			{
+				DNSName:    "test.com",
+				RecordType: "MX",
+				RecordTTL:  120,
+				Targets:    endpoint.Targets{"10 mail.test.com."},
+				Labels:     endpoint.Labels{},
+			},
+			{
+				DNSName:    "mail.test.com",
+				RecordType: "A",
+				RecordTTL:  300,
+				Targets:    endpoint.Targets{"1.2.3.4"},
+				Labels:     endpoint.Labels{},
+			},
+		}, endpoints)
+	})
+
+	t.Run("Invalid zone file", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ParseZoneFile(strings.NewReader("test.com. 300 IN A not-an-ip"), "")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,109 @@
+package selprovider
+
+import (
+	"sync"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// zoneRecordsCache keeps the endpoints of every zone read by the last scan together with the modification time of
+// the zone, so the record sets of unchanged zones are not read again until the next full scan or until they reach
+// the maximum age.
+type zoneRecordsCache struct {
+	mu           sync.Mutex
+	zones        map[string]*zoneRecords
+	lastFullScan time.Time
+	// generation is increased by invalidate and reset, so a scan started before does not fill the cache
+	generation uint64
+}
+
+type zoneRecords struct {
+	updatedAt time.Time
+	// readAt is the start of the scan that read the record sets, it is kept while the zone is served from the cache
+	readAt    time.Time
+	endpoints []*endpoint.Endpoint
+}
+
+// zoneScan is the state of a single scan of the zones.
+type zoneScan struct {
+	full       bool
+	generation uint64
+	startedAt  time.Time
+}
+
+// startScan starts a scan, which reads all zones if the last full scan is older than the resync interval.
+func (c *zoneRecordsCache) startScan(resyncInterval time.Duration) zoneScan {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	return zoneScan{
+		full:       c.lastFullScan.IsZero() || now.Sub(c.lastFullScan) >= resyncInterval,
+		generation: c.generation,
+		startedAt:  now,
+	}
+}
+
+// lookup returns the cached records of the zone if it was not modified since they were read. Records older than the
+// given maximum age are read again regardless of the modification time, in case the API did not update it.
+func (c *zoneRecordsCache) lookup(zone *domains.Zone, maxAge time.Duration) (*zoneRecords, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.zones[zone.ID]
+	if !ok || zone.UpdatedAt.IsZero() || !cached.updatedAt.Equal(zone.UpdatedAt) {
+		return nil, false
+	}
+
+	if maxAge > 0 && time.Since(cached.readAt) >= maxAge {
+		return nil, false
+	}
+
+	return cached, true
+}
+
+// store replaces the cache by the records of the scanned zones, which removes the zones that are gone. Nothing is
+// stored if the cache was invalidated during the scan. Zones without modification time are always read.
+func (c *zoneRecordsCache) store(scan zoneScan, zones []*domains.Zone, recordsPerZone []*zoneRecords) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != scan.generation {
+		return
+	}
+
+	c.zones = make(map[string]*zoneRecords, len(zones))
+	for i, zone := range zones {
+		if zone.UpdatedAt.IsZero() {
+			continue
+		}
+		c.zones[zone.ID] = recordsPerZone[i]
+	}
+
+	if scan.full {
+		c.lastFullScan = scan.startedAt
+	}
+}
+
+// invalidate drops the endpoints of a zone changed by the provider, in case the API did not update its modification
+// time.
+func (c *zoneRecordsCache) invalidate(zoneID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.zones, zoneID)
+	c.generation++
+}
+
+// reset drops all endpoints, e.g. when the domain filter changed.
+func (c *zoneRecordsCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.zones = nil
+	c.lastFullScan = time.Time{}
+	c.generation++
+}
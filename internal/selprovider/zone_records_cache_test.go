@@ -0,0 +1,215 @@
+package selprovider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// rrSetReads counts the requests listing the record sets of a zone by zone id.
+type rrSetReads struct {
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (r *rrSetReads) take() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reads := r.reads
+	r.reads = make(map[string]int)
+
+	return reads
+}
+
+func getCountingFakeServer(t *testing.T, fake *fakeselectel.Server) (*httptest.Server, *rrSetReads) {
+	t.Helper()
+
+	reads := &rrSetReads{reads: make(map[string]int)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, fakeselectel.DomainsPath+"/zones/")
+		if zoneID, found := strings.CutSuffix(path, "/rrset"); found && r.Method == http.MethodGet {
+			reads.mu.Lock()
+			reads.reads[zoneID]++
+			reads.mu.Unlock()
+		}
+		fake.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, reads
+}
+
+func TestIncrementalRecords(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	first, err := fake.AddZone("first.com")
+	assert.NoError(t, err)
+	second, err := fake.AddZone("second.com")
+	assert.NoError(t, err)
+	server, reads := getCountingFakeServer(t, fake)
+
+	dnsProvider, err := New(Config{
+		BaseURL:            server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider:   getDefaultKeystoneProvider(t, 5),
+		DomainFilter:       endpoint.NewDomainFilter([]string{"first.com", "second.com"}),
+		Workers:            2,
+		FullResyncInterval: time.Hour,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	// the first scan is full
+	endpoints, err := dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, endpoints)
+	assert.Equal(t, map[string]int{first.ID: 1, second.ID: 1}, reads.take())
+
+	// no zone changed
+	_, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, reads.take())
+
+	// a change outside of the webhook updates the modification time of the zone
+	_, err = fake.AddRRSet(second.ID, domains.RRSet{
+		Name:    "www.second.com.",
+		Type:    domains.A,
+		TTL:     300,
+		Records: []domains.RecordItem{{Content: "1.1.1.1"}},
+	})
+	assert.NoError(t, err)
+
+	endpoints, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 1)
+	assert.Equal(t, map[string]int{second.ID: 1}, reads.take())
+
+	// a change of the webhook invalidates the zone
+	assert.NoError(t, dnsProvider.ApplyChanges(context.Background(), &plan.Changes{
+		Create: []*endpoint.Endpoint{
+			{DNSName: "www.first.com", RecordType: "A", Targets: endpoint.Targets{"2.2.2.2"}},
+		},
+	}))
+	reads.take()
+
+	endpoints, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, map[string]int{first.ID: 1}, reads.take())
+}
+
+func TestIncrementalRecordsFullResync(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+	server, reads := getCountingFakeServer(t, fake)
+
+	dnsProvider, err := New(Config{
+		BaseURL:            server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider:   getDefaultKeystoneProvider(t, 2),
+		DomainFilter:       endpoint.NewDomainFilter([]string{"example.com"}),
+		Workers:            1,
+		FullResyncInterval: time.Nanosecond,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	for range 2 {
+		_, err = dnsProvider.Records(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int{zone.ID: 1}, reads.take())
+	}
+}
+
+func TestZoneRecordsCacheInvalidatedDuringScan(t *testing.T) {
+	t.Parallel()
+
+	var cache zoneRecordsCache
+	zone := &domains.Zone{ID: "1234", UpdatedAt: time.Now()}
+
+	scan := cache.startScan(time.Hour)
+	assert.True(t, scan.full)
+	cache.invalidate(zone.ID)
+	cache.store(scan, []*domains.Zone{zone}, []*zoneRecords{{updatedAt: zone.UpdatedAt, readAt: scan.startedAt}})
+
+	// the scan may have read the record sets before the change
+	_, ok := cache.lookup(zone, time.Hour)
+	assert.False(t, ok)
+	assert.True(t, cache.startScan(time.Hour).full)
+
+	scan = cache.startScan(time.Hour)
+	cache.store(scan, []*domains.Zone{zone}, []*zoneRecords{{updatedAt: zone.UpdatedAt, readAt: scan.startedAt}})
+	_, ok = cache.lookup(zone, time.Hour)
+	assert.True(t, ok)
+	assert.False(t, cache.startScan(time.Hour).full)
+
+	// zones without modification time are always read
+	_, ok = cache.lookup(&domains.Zone{ID: zone.ID}, time.Hour)
+	assert.False(t, ok)
+}
+
+func TestZoneRecordsCacheMaxAge(t *testing.T) {
+	t.Parallel()
+
+	var cache zoneRecordsCache
+	zone := &domains.Zone{ID: "1234", UpdatedAt: time.Now()}
+
+	scan := cache.startScan(time.Hour)
+	scan.startedAt = time.Now().Add(-time.Minute)
+	cache.store(scan, []*domains.Zone{zone}, []*zoneRecords{{updatedAt: zone.UpdatedAt, readAt: scan.startedAt}})
+
+	_, ok := cache.lookup(zone, time.Hour)
+	assert.True(t, ok)
+
+	// the zone was not modified, but the records are older than the maximum age
+	_, ok = cache.lookup(zone, time.Minute)
+	assert.False(t, ok)
+}
+
+func TestIncrementalRecordsMaxAge(t *testing.T) {
+	t.Parallel()
+
+	fake := fakeselectel.New(fakeselectel.Config{})
+	fake.AddToken("test")
+	first, err := fake.AddZone("first.com")
+	assert.NoError(t, err)
+	server, reads := getCountingFakeServer(t, fake)
+
+	dnsProvider, err := New(Config{
+		BaseURL:            server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider:   getDefaultKeystoneProvider(t, 3),
+		DomainFilter:       endpoint.NewDomainFilter([]string{"first.com"}),
+		Workers:            1,
+		FullResyncInterval: time.Hour,
+		ZoneRecordsMaxAge:  200 * time.Millisecond,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	_, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{first.ID: 1}, reads.take())
+
+	_, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, reads.take())
+
+	// the scan is not full and updated_at did not change, but the cached records expired
+	time.Sleep(200 * time.Millisecond)
+	_, err = dnsProvider.Records(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{first.ID: 1}, reads.take())
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+	"go.uber.org/zap"
+)
+
+// NewAccessLogMiddleware logs every request through the given logger. Like the logger middleware of fiber, errors
+// of the handlers are passed to the error handler first, so the logged status is the one sent to the client.
+func NewAccessLogMiddleware(logger *zap.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+
+		chainErr := c.Next()
+		if chainErr != nil {
+			if err := c.App().ErrorHandler(c, chainErr); err != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		// the values of fiber are only valid within the handler, so they are copied
+		status := c.Response().StatusCode()
+		fields := []zap.Field{
+			zap.String("method", utils.CopyString(c.Method())),
+			zap.String("path", utils.CopyString(c.Path())),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("ip", utils.CopyString(c.IP())),
+		}
+		if id := c.Get(requestid.Header); id != "" {
+			fields = append(fields, zap.String("request_id", utils.CopyString(id)))
+		}
+		if chainErr != nil {
+			fields = append(fields, zap.Error(chainErr))
+		}
+
+		if status >= fiber.StatusInternalServerError {
+			logger.Error("request", fields...)
+		} else {
+			logger.Info("request", fields...)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	mockProvider := mock_provider.NewMockProvider(ctrl)
+	app := api.New(zap.New(core), getTestMockMetricsCollector(ctrl), mockProvider)
+	mockProvider.EXPECT().Records(gomock.Any()).Return([]*endpoint.Endpoint{}, nil).Times(1)
+
+	req := httptest.NewRequest(http.MethodGet, "/records", nil)
+	req.Header.Set(requestid.Header, "sync-1")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	entries := logs.Filter(func(entry observer.LoggedEntry) bool {
+		return entry.LoggerName == "access"
+	}).AllUntimed()
+	assert.Len(t, entries, 2)
+
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "request", entries[0].Message)
+	assert.Equal(t, "GET", fields["method"])
+	assert.Equal(t, "/records", fields["path"])
+	assert.Equal(t, int64(http.StatusOK), fields["status"])
+	assert.Equal(t, "sync-1", fields["request_id"])
+
+	fields = entries[1].ContextMap()
+	assert.Equal(t, "/unknown", fields["path"])
+	assert.Equal(t, int64(http.StatusNotFound), fields["status"])
+	assert.Contains(t, fields["error"], "Cannot GET /unknown")
+}
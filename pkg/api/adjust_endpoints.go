@@ -26,9 +26,9 @@ func (w webhook) AdjustEndpoints(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	w.logger.Debug("adjusted endpoints", zap.String("endpoints", fmt.Sprintf("%v", pve)))
-
-	ctx.Set(varyHeader, contentTypeHeader)
+	if entry := w.logger.Check(zap.DebugLevel, "adjusted endpoints"); entry != nil {
+		entry.Write(zap.String("endpoints", fmt.Sprintf("%v", pve)))
+	}
 
-	return ctx.JSON(pve)
+	return sendJSON(ctx, pve)
 }
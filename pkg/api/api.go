@@ -2,19 +2,21 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/goccy/go-json"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/helmet"
-	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	fiberrecover "github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/metrics"
 	"go.uber.org/zap"
 	"sigs.k8s.io/external-dns/provider"
@@ -22,19 +24,29 @@ import (
 
 type Api interface {
 	Listen(port string) error
+	Shutdown() error
 	Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error)
 }
 
+// defaultShutdownGracePeriod is the time given to the running requests to finish on shutdown.
+const defaultShutdownGracePeriod = 30 * time.Second
+
 type api struct {
-	logger *zap.Logger
-	app    *fiber.App
+	logger   *zap.Logger
+	app      *fiber.App
+	provider provider.Provider
+	ready    atomic.Bool
+
+	readinessDelay      time.Duration
+	shutdownGracePeriod time.Duration
 }
 
-func (a api) Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error) {
+func (a *api) Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error) {
 	return a.app.Test(req, msTimeout...)
 }
 
-func (a api) Listen(port string) error {
+// Listen serves the api on the given port until SIGINT, SIGTERM or SIGQUIT is received and shuts it down then.
+func (a *api) Listen(port string) error {
 	go func() {
 		err := a.app.Listen(fmt.Sprintf(":%s", port))
 		if err != nil {
@@ -43,21 +55,53 @@ func (a api) Listen(port string) error {
 	}()
 
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	sig := <-sigCh
 
 	a.logger.Info(
 		"shutting down server due to received signal",
 		zap.String("signal", sig.String()),
+		zap.Duration("readinessDelay", a.readinessDelay),
+		zap.Duration("gracePeriod", a.shutdownGracePeriod),
 	)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	err := a.app.ShutdownWithContext(ctx)
+	return a.Shutdown()
+}
+
+// Shutdown reports the api as not ready and keeps serving for the readiness delay, so the readiness probe takes the
+// webhook out of the service endpoints first. Then it stops accepting new requests and waits for the running ones
+// within the first half of the grace period. Providers implementing Drain get the rest of the grace period to finish
+// or stop their running changes, the changes left undone are logged.
+func (a *api) Shutdown() error {
+	a.ready.Store(false)
+	time.Sleep(a.readinessDelay)
+
+	deadline := time.Now().Add(a.shutdownGracePeriod)
+
+	serverCtx, cancelServer := context.WithTimeout(context.Background(), a.shutdownGracePeriod/2)
+	defer cancelServer()
+
+	err := a.app.ShutdownWithContext(serverCtx)
 	if err != nil {
 		a.logger.Error("error shutting down server", zap.String("err", err.Error()))
 	}
 
-	cancel()
+	if drainer, ok := a.provider.(drainer); ok {
+		// the drain gets at least half of the grace period, even if the server shutdown used up its own half
+		drainCtx, cancelDrain := context.WithDeadline(context.Background(), deadline)
+		defer cancelDrain()
+
+		drainErr := drainer.Drain(drainCtx)
+		var undoneErr undoneChangesError
+		if errors.As(drainErr, &undoneErr) {
+			a.logger.Warn(
+				"changes left undone on shutdown, they are applied by the next run of external-dns",
+				zap.Strings("undone", undoneErr.UndoneChanges()),
+			)
+		} else if drainErr != nil {
+			a.logger.Error("error draining provider", zap.String(logFieldError, drainErr.Error()))
+		}
+	}
 
 	return err
 }
@@ -67,34 +111,120 @@ type Provider interface {
 	provider.Provider
 }
 
-func New(logger *zap.Logger, middlewareCollector metrics.HttpApiMetrics, provider provider.Provider) Api {
+// Option configures the api created by New.
+type Option func(*options)
+
+type options struct {
+	requestTimeout      time.Duration
+	readinessDelay      time.Duration
+	shutdownGracePeriod time.Duration
+
+	logLevels           *logging.Levels
+	logLevelToken       string
+	logLevelRevertAfter time.Duration
+}
+
+// WithRequestTimeout sets the deadline of the Records and ApplyChanges provider calls. A request exceeding it is
+// answered with 504 Gateway Timeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.requestTimeout = timeout
+	}
+}
+
+// WithReadinessDelay sets the time the api keeps serving on shutdown after /readyz started failing. Defaults to 0.
+func WithReadinessDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.readinessDelay = delay
+	}
+}
+
+// WithShutdownGracePeriod sets the time given to the running requests to finish on shutdown. The first half is given
+// to the server shutdown, after the whole grace period the running changes of a draining provider are stopped.
+// Defaults to 30 seconds.
+func WithShutdownGracePeriod(gracePeriod time.Duration) Option {
+	return func(o *options) {
+		o.shutdownGracePeriod = gracePeriod
+	}
+}
+
+// WithLogLevels serves the levels of the logger on /debug/loglevel for requests with the given bearer token. Changes
+// are reverted after revertAfter unless the request sets another duration. The token also guards /debug/dry-run.
+// Both endpoints are disabled if the token is empty.
+func WithLogLevels(levels *logging.Levels, token string, revertAfter time.Duration) Option {
+	return func(o *options) {
+		o.logLevels = levels
+		o.logLevelToken = token
+		o.logLevelRevertAfter = revertAfter
+	}
+}
+
+func New(
+	logger *zap.Logger,
+	middlewareCollector metrics.HttpApiMetrics,
+	provider provider.Provider,
+	opts ...Option,
+) Api {
+	o := options{shutdownGracePeriod: defaultShutdownGracePeriod}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 		JSONEncoder:           json.Marshal,
 		JSONDecoder:           json.Unmarshal,
 	})
 
+	a := &api{
+		logger:              logger,
+		app:                 app,
+		provider:            provider,
+		readinessDelay:      o.readinessDelay,
+		shutdownGracePeriod: o.shutdownGracePeriod,
+	}
+	a.ready.Store(true)
+
 	registerAt(app, "/metrics")
 	app.Get("/healthz", Health)
+	app.Get("/readyz", Readiness(&a.ready))
 
 	app.Use(NewMetricsMiddleware(middlewareCollector))
-	app.Use(fiberlogger.New())
+	app.Use(NewAccessLogMiddleware(logger.Named("access")))
 	app.Use(pprof.New(pprof.Config{Prefix: "/pprof"}))
 	app.Use(fiberrecover.New())
 	app.Use(helmet.New())
+	app.Use(NewRequestIDMiddleware())
 
 	webhookRoutes := webhook{
 		provider: provider,
 		logger:   logger,
 	}
 
-	app.Get("/records", webhookRoutes.Records)
-	app.Get("/", webhookRoutes.GetDomainFilter)
-	app.Post("/records", webhookRoutes.ApplyChanges)
-	app.Post("/adjustendpoints", webhookRoutes.AdjustEndpoints)
+	negotiateMediaType := NewMediaTypeMiddleware()
+	requestTimeout := NewTimeoutMiddleware(o.requestTimeout)
+
+	app.Get("/records", negotiateMediaType, requestTimeout, webhookRoutes.Records)
+	app.Get("/", negotiateMediaType, webhookRoutes.GetDomainFilter)
+	app.Post("/records", negotiateMediaType, requestTimeout, webhookRoutes.ApplyChanges)
+	app.Post("/adjustendpoints", negotiateMediaType, webhookRoutes.AdjustEndpoints)
 
-	return &api{
-		logger: logger,
-		app:    app,
+	// the debug endpoints expose the planned changes and the logging, they are only served with a token
+	if o.logLevelToken != "" {
+		authenticate := NewTokenAuthMiddleware(o.logLevelToken)
+		app.Get("/debug/dry-run", authenticate, webhookRoutes.DryRunReport)
+
+		if o.logLevels != nil {
+			logLevelRoutes := logLevels{
+				levels:      o.logLevels,
+				revertAfter: o.logLevelRevertAfter,
+				logger:      logger,
+			}
+
+			app.Get("/debug/loglevel", authenticate, logLevelRoutes.Get)
+			app.Put("/debug/loglevel", authenticate, logLevelRoutes.Set)
+		}
 	}
+
+	return a
 }
@@ -1,6 +1,7 @@
 package api_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -13,6 +14,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestApi(t *testing.T) {
@@ -53,3 +56,106 @@ func TestApi(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+// drainingProvider records the deadline of Drain and returns the given error.
+type drainingProvider struct {
+	*mock_provider.MockProvider
+	drainErr error
+	drained  bool
+	deadline time.Time
+}
+
+func (p *drainingProvider) Drain(ctx context.Context) error {
+	p.deadline, p.drained = ctx.Deadline()
+
+	return p.drainErr
+}
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		drainErr        error
+		expectedWarning bool
+	}{
+		{
+			name: "Running changes finished",
+		},
+		{
+			name:            "Changes left undone",
+			drainErr:        undoneChangesError{"CREATE www.example.com A"},
+			expectedWarning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			provider := &drainingProvider{MockProvider: mock_provider.NewMockProvider(ctrl), drainErr: tt.drainErr}
+			app := api.New(zap.New(core), getTestMockMetricsCollector(ctrl), provider,
+				api.WithShutdownGracePeriod(time.Second))
+
+			assert.NoError(t, app.Shutdown())
+			assert.True(t, provider.drained)
+			// the drain gets the rest of the grace period
+			assert.Greater(t, time.Until(provider.deadline), 500*time.Millisecond)
+
+			warnings := logs.FilterField(zap.Strings("undone", []string{"CREATE www.example.com A"})).Len()
+			if tt.expectedWarning {
+				assert.Equal(t, 1, warnings)
+			} else {
+				assert.Zero(t, logs.Len())
+			}
+		})
+	}
+}
+
+func TestShutdownReadinessDelay(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mock_provider.NewMockProvider(ctrl),
+		api.WithReadinessDelay(200*time.Millisecond))
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- app.Shutdown()
+	}()
+
+	// requests are still served while the readiness probe fails
+	assert.Eventually(t, func() bool {
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+
+		return err == nil && resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	select {
+	case <-shutdown:
+		t.Fatal("server stopped before the readiness delay ended")
+	default:
+	}
+	assert.NoError(t, <-shutdown)
+}
+
+type undoneChangesError []string
+
+func (e undoneChangesError) Error() string {
+	return "changes left undone"
+}
+
+func (e undoneChangesError) UndoneChanges() []string {
+	return e
+}
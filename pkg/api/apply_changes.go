@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,16 +19,41 @@ func (w webhook) ApplyChanges(ctx *fiber.Ctx) error {
 		return ctx.Status(fiber.StatusBadRequest).SendString(err.Error())
 	}
 
-	w.logger.Debug(
-		"requesting apply changes",
-		zap.String("create", fmt.Sprintf("%v", changes.Create)),
-		zap.String("delete", fmt.Sprintf("%v", changes.Delete)),
-		zap.String("updateNew", fmt.Sprintf("%v", changes.UpdateNew)),
-		zap.String("updateOld", fmt.Sprintf("%v", changes.UpdateOld)),
-		zap.String("updateNew", fmt.Sprintf("%v", changes.UpdateNew)),
-	)
+	// the changes are only formatted if debug logging is enabled
+	if entry := w.logger.Check(zap.DebugLevel, "requesting apply changes"); entry != nil {
+		entry.Write(
+			zap.String("create", fmt.Sprintf("%v", changes.Create)),
+			zap.String("delete", fmt.Sprintf("%v", changes.Delete)),
+			zap.String("updateNew", fmt.Sprintf("%v", changes.UpdateNew)),
+			zap.String("updateOld", fmt.Sprintf("%v", changes.UpdateOld)),
+		)
+	}
 
 	err = w.provider.ApplyChanges(ctx.UserContext(), &changes)
+	var refusedErr refusedBatchError
+	if errors.As(err, &refusedErr) {
+		// external-dns retries on 5xx, so the batch is refused again until it is within the limits
+		w.logger.Warn("Batch of changes was refused", zap.Strings("refused", refusedErr.RefusedChanges()))
+
+		return sendJSON(ctx.Status(fiber.StatusServiceUnavailable), BlockedChangesMessage{
+			Message: refusedErr.Error(),
+			Blocked: refusedErr.RefusedChanges(),
+		})
+	}
+
+	var blockedErr blockedChangesError
+	if errors.As(err, &blockedErr) {
+		// the other changes were applied and external-dns exits on any status but 204 and 5xx, a 204 has no body,
+		// so every blocked change is reported in a header of its own
+		w.logger.Warn("Some changes were blocked", zap.Strings("blocked", blockedErr.BlockedChanges()))
+		for _, blocked := range blockedErr.BlockedChanges() {
+			ctx.Response().Header.Add(blockedChangesHeader, blocked)
+		}
+		ctx.Status(fiber.StatusNoContent)
+
+		return nil
+	}
+
 	if err != nil {
 		w.logger.Error("Error applying changes", zap.String(logFieldError, err.Error()))
 		ctx.Response().Header.Set(contentTypeHeader, contentTypePlaintext)
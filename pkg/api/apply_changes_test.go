@@ -44,6 +44,7 @@ func TestWebhook_ApplyChanges(t *testing.T) {
 		resp, err := app.Test(req, -1)
 		assert.NoError(t, err)
 		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Empty(t, resp.Header.Values("X-External-DNS-Blocked"))
 	})
 
 	t.Run("Invalid data send by client", func(t *testing.T) {
@@ -81,6 +82,53 @@ func TestWebhook_ApplyChanges(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, respFail.StatusCode)
 	})
 
+	t.Run("Provider blocks changes", func(t *testing.T) {
+		t.Parallel()
+
+		mockLogger := zap.NewNop()
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		mockMetricsCollector := getTestMockMetricsCollector(ctrl)
+
+		app := api.New(mockLogger, mockMetricsCollector, mockProvider)
+		mockProvider.EXPECT().ApplyChanges(gomock.Any(), changes).Return(
+			fmt.Errorf("apply: %w", blockedError{blocked: []string{"DELETE test.delete.com A blocked"}}),
+		).Times(1)
+
+		req := httptest.NewRequest(http.MethodPost, "/records", bytes.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		// external-dns exits on any other status than 204 and 5xx
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, []string{"DELETE test.delete.com A blocked"}, resp.Header.Values("X-External-DNS-Blocked"))
+	})
+
+	t.Run("Provider refuses batch", func(t *testing.T) {
+		t.Parallel()
+
+		mockLogger := zap.NewNop()
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		mockMetricsCollector := getTestMockMetricsCollector(ctrl)
+
+		app := api.New(mockLogger, mockMetricsCollector, mockProvider)
+		mockProvider.EXPECT().ApplyChanges(gomock.Any(), changes).Return(
+			fmt.Errorf("apply: %w", refusedError{refused: []string{"DELETE test.delete.com A"}}),
+		).Times(1)
+
+		req := httptest.NewRequest(http.MethodPost, "/records", bytes.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		// external-dns retries the batch on 5xx
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		var message api.BlockedChangesMessage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		assert.Equal(t, []string{"DELETE test.delete.com A"}, message.Blocked)
+	})
+
 	t.Run("Client send invalid JSON", func(t *testing.T) {
 		t.Parallel()
 
@@ -99,6 +147,30 @@ func TestWebhook_ApplyChanges(t *testing.T) {
 	})
 }
 
+type blockedError struct {
+	blocked []string
+}
+
+func (e blockedError) Error() string {
+	return "changes blocked"
+}
+
+func (e blockedError) BlockedChanges() []string {
+	return e.blocked
+}
+
+type refusedError struct {
+	refused []string
+}
+
+func (e refusedError) Error() string {
+	return "batch refused"
+}
+
+func (e refusedError) RefusedChanges() []string {
+	return e.refused
+}
+
 func getValidPlanChanges() *plan.Changes {
 	changes := &plan.Changes{
 		Create: []*endpoint.Endpoint{
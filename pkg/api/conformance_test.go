@@ -0,0 +1,239 @@
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/selectel/external-dns-selectel-webhook/internal/selprovider"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/fakeselectel"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/keystone"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+const webhookMediaType = "application/external.dns.webhook+json;version=1"
+
+// TestConformance drives the external-dns webhook protocol against the real provider and the fake Selectel DNS API
+// the same way the external-dns webhook provider and its controller loop do.
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	suite := newConformanceSuite(t)
+
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeA, 300, "192.0.2.1", "192.0.2.2"),
+		endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeTXT, 300, "\"heritage=external-dns\""),
+		endpoint.NewEndpointWithTTL("v6.example.com", endpoint.RecordTypeAAAA, 60, "2001:db8::1"),
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeCNAME, 120, "example.com"),
+	}
+
+	t.Run("Negotiation", func(t *testing.T) {
+		resp := suite.do(http.MethodGet, "/", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, webhookMediaType, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "Content-Type, Accept", resp.Header.Get("Vary"))
+
+		var domainFilter endpoint.DomainFilter
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&domainFilter))
+		assert.Equal(t, []string{"example.com"}, domainFilter.Filters)
+	})
+
+	t.Run("Records of empty zone", func(t *testing.T) {
+		assert.Empty(t, suite.records())
+	})
+
+	t.Run("AdjustEndpoints keeps endpoints", func(t *testing.T) {
+		assert.Equal(t, normalizeEndpoints(desired), normalizeEndpoints(suite.adjustEndpoints(desired)))
+	})
+
+	t.Run("ApplyChanges creates records", func(t *testing.T) {
+		changes := suite.plan(desired)
+		assert.Len(t, changes.Create, len(desired))
+
+		suite.applyChanges(changes)
+		assert.Equal(t, normalizeEndpoints(desired), normalizeEndpoints(suite.records()))
+	})
+
+	t.Run("Idempotency", func(t *testing.T) {
+		zoneBefore := suite.fake.RRSets(suite.zoneID)
+
+		changes := suite.plan(desired)
+		assert.False(t, changes.HasChanges())
+
+		suite.applyChanges(changes)
+		assert.Equal(t, zoneBefore, suite.fake.RRSets(suite.zoneID))
+		assert.Equal(t, normalizeEndpoints(desired), normalizeEndpoints(suite.records()))
+	})
+
+	t.Run("ApplyChanges updates records", func(t *testing.T) {
+		desired[0] = endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeA, 600, "192.0.2.3")
+
+		changes := suite.plan(desired)
+		assert.Len(t, changes.UpdateNew, 1)
+		assert.Empty(t, changes.Create)
+		assert.Empty(t, changes.Delete)
+
+		suite.applyChanges(changes)
+		assert.Equal(t, normalizeEndpoints(desired), normalizeEndpoints(suite.records()))
+	})
+
+	t.Run("ApplyChanges deletes records", func(t *testing.T) {
+		desired = desired[:3]
+
+		changes := suite.plan(desired)
+		assert.Len(t, changes.Delete, 1)
+
+		suite.applyChanges(changes)
+		assert.Equal(t, normalizeEndpoints(desired), normalizeEndpoints(suite.records()))
+		assert.Len(t, suite.fake.RRSets(suite.zoneID), 3)
+	})
+}
+
+type conformanceSuite struct {
+	t      *testing.T
+	app    api.Api
+	fake   *fakeselectel.Server
+	zoneID string
+}
+
+func newConformanceSuite(t *testing.T) *conformanceSuite {
+	t.Helper()
+
+	fake := fakeselectel.New(fakeselectel.Config{Username: "user", Password: "secret"})
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+	_, err = fake.AddZone("example.org")
+	assert.NoError(t, err)
+
+	dnsProvider, err := selprovider.New(selprovider.Config{
+		BaseURL: server.URL + fakeselectel.DomainsPath,
+		KeystoneProvider: keystone.NewProvider(zap.NewNop(), keystone.Credentials{
+			IdentityEndpoint: server.URL + fakeselectel.KeystonePath,
+			AccountID:        "account",
+			ProjectID:        "project",
+			Username:         "user",
+			Password:         "secret",
+		}, nil),
+		DomainFilter: endpoint.DomainFilter{Filters: []string{"example.com"}},
+		Workers:      2,
+	}, zap.NewNop())
+	assert.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+
+	return &conformanceSuite{
+		t:      t,
+		app:    api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), dnsProvider),
+		fake:   fake,
+		zoneID: zone.ID,
+	}
+}
+
+// do sends a request with the headers of the external-dns webhook provider.
+func (s *conformanceSuite) do(method, path string, body any) *http.Response {
+	s.t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		assert.NoError(s.t, err)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Accept", webhookMediaType)
+	if body != nil {
+		req.Header.Set("Content-Type", webhookMediaType)
+	}
+
+	resp, err := s.app.Test(req, -1)
+	assert.NoError(s.t, err)
+
+	return resp
+}
+
+func (s *conformanceSuite) records() []*endpoint.Endpoint {
+	s.t.Helper()
+
+	resp := s.do(http.MethodGet, "/records", nil)
+	assert.Equal(s.t, http.StatusOK, resp.StatusCode)
+	assertJSONResponse(s.t, resp)
+
+	var records []*endpoint.Endpoint
+	assert.NoError(s.t, json.NewDecoder(resp.Body).Decode(&records))
+
+	return records
+}
+
+func (s *conformanceSuite) adjustEndpoints(endpoints []*endpoint.Endpoint) []*endpoint.Endpoint {
+	s.t.Helper()
+
+	resp := s.do(http.MethodPost, "/adjustendpoints", endpoints)
+	assert.Equal(s.t, http.StatusOK, resp.StatusCode)
+	assertJSONResponse(s.t, resp)
+
+	var adjusted []*endpoint.Endpoint
+	assert.NoError(s.t, json.NewDecoder(resp.Body).Decode(&adjusted))
+
+	return adjusted
+}
+
+func (s *conformanceSuite) applyChanges(changes *plan.Changes) {
+	s.t.Helper()
+
+	resp := s.do(http.MethodPost, "/records", changes)
+	assert.Equal(s.t, http.StatusNoContent, resp.StatusCode)
+}
+
+// plan calculates the changes like the external-dns controller does with the upsert-only policy disabled.
+func (s *conformanceSuite) plan(desired []*endpoint.Endpoint) *plan.Changes {
+	s.t.Helper()
+
+	calculated := (&plan.Plan{
+		Current:  s.records(),
+		Desired:  s.adjustEndpoints(desired),
+		Policies: []plan.Policy{&plan.SyncPolicy{}},
+		ManagedRecords: []string{
+			endpoint.RecordTypeA, endpoint.RecordTypeAAAA, endpoint.RecordTypeCNAME, endpoint.RecordTypeTXT,
+		},
+	}).Calculate()
+
+	return calculated.Changes
+}
+
+func assertJSONResponse(t *testing.T, resp *http.Response) {
+	t.Helper()
+
+	assert.Equal(t, webhookMediaType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "Content-Type, Accept", resp.Header.Get("Vary"))
+}
+
+// normalizeEndpoints returns the endpoints as comparable strings independent of order of endpoints and targets.
+func normalizeEndpoints(endpoints []*endpoint.Endpoint) []string {
+	result := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		targets := append([]string(nil), ep.Targets...)
+		sort.Strings(targets)
+		result = append(result, fmt.Sprintf(
+			"%s %s %d %s", ep.DNSName, ep.RecordType, ep.RecordTTL, strings.Join(targets, ","),
+		))
+	}
+	sort.Strings(result)
+
+	return result
+}
@@ -11,8 +11,7 @@ func (w webhook) GetDomainFilter(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	ctx.Set(varyHeader, contentTypeHeader)
-	ctx.Set(contentTypeHeader, mediaTypeFormat)
+	ctx.Set(contentTypeHeader, getProtocolVersion(ctx).mediaType)
 
 	return ctx.Send(data)
 }
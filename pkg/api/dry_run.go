@@ -0,0 +1,28 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// dryRunReporter is implemented by providers that keep the diff computed by the last ApplyChanges in dry-run mode.
+type dryRunReporter interface {
+	DryRunReport() any
+}
+
+func (w webhook) DryRunReport(ctx *fiber.Ctx) error {
+	reporter, ok := w.provider.(dryRunReporter)
+	if !ok {
+		return ctx.Status(fiber.StatusNotFound).JSON(Message{
+			Message: "provider does not support dry run reports",
+		})
+	}
+
+	report := reporter.DryRunReport()
+	if report == nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(Message{
+			Message: "no dry run report available",
+		})
+	}
+
+	return ctx.JSON(report)
+}
@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+type dryRunProvider struct {
+	*mock_provider.MockProvider
+	report any
+}
+
+func (p dryRunProvider) DryRunReport() any {
+	return p.report
+}
+
+// getDryRunRequest returns a request of the dry run report with the given bearer token.
+func getDryRunRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/debug/dry-run", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req
+}
+
+func TestWebhook_DryRunReport(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	t.Run("Provider returns report", func(t *testing.T) {
+		t.Parallel()
+
+		provider := dryRunProvider{
+			MockProvider: mock_provider.NewMockProvider(ctrl),
+			report:       map[string]string{"zone": "test.com"},
+		}
+		app := api.New(
+			zap.NewNop(), getTestMockMetricsCollector(ctrl), provider, api.WithLogLevels(nil, "secret-token", time.Hour),
+		)
+
+		resp, err := app.Test(getDryRunRequest("secret-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var report map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		assert.Equal(t, "test.com", report["zone"])
+	})
+
+	t.Run("Provider has no report yet", func(t *testing.T) {
+		t.Parallel()
+
+		provider := dryRunProvider{MockProvider: mock_provider.NewMockProvider(ctrl)}
+		app := api.New(
+			zap.NewNop(), getTestMockMetricsCollector(ctrl), provider, api.WithLogLevels(nil, "secret-token", time.Hour),
+		)
+
+		resp, err := app.Test(getDryRunRequest("secret-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Provider does not support reports", func(t *testing.T) {
+		t.Parallel()
+
+		app := api.New(
+			zap.NewNop(),
+			getTestMockMetricsCollector(ctrl),
+			mock_provider.NewMockProvider(ctrl),
+			api.WithLogLevels(nil, "secret-token", time.Hour),
+		)
+
+		resp, err := app.Test(getDryRunRequest("secret-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Request without token", func(t *testing.T) {
+		t.Parallel()
+
+		provider := dryRunProvider{MockProvider: mock_provider.NewMockProvider(ctrl), report: "report"}
+		app := api.New(
+			zap.NewNop(), getTestMockMetricsCollector(ctrl), provider, api.WithLogLevels(nil, "secret-token", time.Hour),
+		)
+
+		resp, err := app.Test(getDryRunRequest(""))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp, err = app.Test(getDryRunRequest("wrong-token"))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("No token configured", func(t *testing.T) {
+		t.Parallel()
+
+		provider := dryRunProvider{MockProvider: mock_provider.NewMockProvider(ctrl), report: "report"}
+		app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), provider)
+
+		resp, err := app.Test(getDryRunRequest(""))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -1,6 +1,10 @@
 package api
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
 
 // Health godoc
 // @Summary Health route
@@ -18,3 +22,27 @@ func Health(c *fiber.Ctx) error {
 		Message: "healthy",
 	})
 }
+
+// Readiness godoc
+// @Summary Readiness route
+// @Description Readiness route, fails once the shutdown started
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} Message
+// @Failure 503 {object} Message
+// @Router /v1/readyz [get]
+// @Tags health
+// get route.
+func Readiness(ready *atomic.Bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !ready.Load() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(Message{
+				Message: "shutting down",
+			})
+		}
+
+		return c.Status(fiber.StatusOK).JSON(Message{
+			Message: "ready",
+		})
+	}
+}
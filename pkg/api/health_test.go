@@ -31,3 +31,22 @@ func TestHealth(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
+
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mock_provider.NewMockProvider(ctrl))
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.NoError(t, app.Shutdown())
+
+	resp, err = app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil), -1)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
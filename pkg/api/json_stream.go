@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/goccy/go-json"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// endpointsEncoder writes endpoints passed in several calls as a single JSON array.
+type endpointsEncoder struct {
+	w            *bufio.Writer
+	started      bool
+	hasEndpoints bool
+}
+
+func newEndpointsEncoder(w io.Writer) *endpointsEncoder {
+	return &endpointsEncoder{w: bufio.NewWriter(w)}
+}
+
+// encode appends the endpoints to the array.
+func (e *endpointsEncoder) encode(endpoints []*endpoint.Endpoint) error {
+	if !e.started {
+		e.w.WriteByte('[')
+		e.started = true
+	}
+
+	for _, ep := range endpoints {
+		data, err := json.Marshal(ep)
+		if err != nil {
+			return err
+		}
+
+		if e.hasEndpoints {
+			e.w.WriteByte(',')
+		}
+		if _, err := e.w.Write(data); err != nil {
+			return err
+		}
+		e.hasEndpoints = true
+	}
+
+	return nil
+}
+
+// close ends the array and flushes it.
+func (e *endpointsEncoder) close() error {
+	if !e.started {
+		e.w.WriteByte('[')
+	}
+	e.w.WriteByte(']')
+
+	return e.w.Flush()
+}
+
+// endpointsStream is the body of a streamed records response. It is read by fasthttp after the handler returned,
+// its Close stops the provider if the client went away.
+type endpointsStream struct {
+	reader *io.PipeReader
+	cancel context.CancelFunc
+
+	// size is the number of bytes read, it is passed to report on Close
+	size   int
+	report func(size int)
+}
+
+func (s *endpointsStream) Read(p []byte) (int, error) {
+	n, err := s.reader.Read(p)
+	s.size += n
+
+	return n, err
+}
+
+// Close is called by fasthttp once the response was written or the connection failed.
+func (s *endpointsStream) Close() error {
+	s.cancel()
+	err := s.reader.Close()
+
+	if s.report != nil {
+		s.report(s.size)
+	}
+
+	return err
+}
+
+func (s *endpointsStream) onWritten(report func(size int)) {
+	s.report = report
+}
+
+// detachContext returns a context that keeps the deadline and values of ctx but is not canceled with it, because the
+// response is streamed after the handler returned and the request context is canceled.
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+
+	return context.WithCancel(detached)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/keyauth"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// LogLevel is the current log level of a component.
+type LogLevel struct {
+	// Component is empty for the global level.
+	Component string `json:"component,omitempty"`
+	Level     string `json:"level"`
+	// RevertAt is the time the level is reverted to the configured one.
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// LogLevelRequest changes the log level of a component or the global level if the component is empty.
+type LogLevelRequest struct {
+	Component string `json:"component,omitempty"`
+	Level     string `json:"level"`
+	// RevertAfter is a duration like 30m, the default revert timeout is used if it is empty.
+	RevertAfter string `json:"revertAfter,omitempty"`
+}
+
+type logLevels struct {
+	levels      *logging.Levels
+	revertAfter time.Duration
+	logger      *zap.Logger
+}
+
+// NewTokenAuthMiddleware only passes requests with the given bearer token in the Authorization header.
+func NewTokenAuthMiddleware(token string) fiber.Handler {
+	return keyauth.New(keyauth.Config{
+		AuthScheme: "Bearer",
+		Validator: func(_ *fiber.Ctx, key string) (bool, error) {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(token)) != 1 {
+				return false, keyauth.ErrMissingOrMalformedAPIKey
+			}
+
+			return true, nil
+		},
+		ErrorHandler: func(c *fiber.Ctx, _ error) error {
+			return c.Status(fiber.StatusUnauthorized).JSON(Message{
+				Message: "missing or invalid token",
+			})
+		},
+	})
+}
+
+func (l logLevels) Get(ctx *fiber.Ctx) error {
+	return ctx.JSON(l.snapshot())
+}
+
+func (l logLevels) Set(ctx *fiber.Ctx) error {
+	var request LogLevelRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(Message{
+			Message: fmt.Sprintf("error parsing log level request: %v", err),
+		})
+	}
+
+	level, err := logging.ParseLevel(request.Level)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(Message{Message: err.Error()})
+	}
+
+	revertAfter := l.revertAfter
+	if request.RevertAfter != "" {
+		revertAfter, err = time.ParseDuration(request.RevertAfter)
+		if err != nil || revertAfter <= 0 {
+			return ctx.Status(fiber.StatusBadRequest).JSON(Message{
+				Message: fmt.Sprintf("invalid revert duration %q", request.RevertAfter),
+			})
+		}
+	}
+
+	if err := l.levels.Set(request.Component, level, revertAfter); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(Message{Message: err.Error()})
+	}
+
+	// logged at warn level to be visible unless only errors are logged
+	l.logger.Warn(
+		"log level changed",
+		zap.String("log_component", request.Component),
+		zap.String("level", level.String()),
+		zap.Duration("revert_after", revertAfter),
+	)
+
+	return ctx.JSON(l.snapshot())
+}
+
+func (l logLevels) snapshot() []LogLevel {
+	snapshot := l.levels.Snapshot()
+
+	levels := make([]LogLevel, 0, len(snapshot))
+	for _, level := range snapshot {
+		logLevel := LogLevel{Component: level.Component, Level: level.Level.String()}
+		if !level.RevertAt.IsZero() {
+			revertAt := level.RevertAt
+			logLevel.RevertAt = &revertAt
+		}
+		levels = append(levels, logLevel)
+	}
+
+	return levels
+}
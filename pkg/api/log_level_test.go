@@ -0,0 +1,151 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestLogLevel(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	logger, levels, err := logging.New(logging.Config{
+		Level:      "info",
+		Format:     logging.FormatJSON,
+		OutputPath: filepath.Join(t.TempDir(), "log"),
+	})
+	assert.NoError(t, err)
+
+	app := api.New(
+		logger.With(zap.String(logging.ComponentKey, "api")),
+		getTestMockMetricsCollector(ctrl),
+		mock_provider.NewMockProvider(ctrl),
+		api.WithLogLevels(levels, "secret-token", time.Hour),
+	)
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		expectedStatus int
+		expectedLevels []string
+		// expectedReverts are the levels reverted automatically
+		expectedReverts []bool
+	}{
+		{
+			name:           "Missing token",
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Invalid token",
+			token:          "wrong-token",
+			body:           `{"level":"debug"}`,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Unknown level",
+			token:          "secret-token",
+			body:           `{"level":"verbose"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown component",
+			token:          "secret-token",
+			body:           `{"component":"keystone","level":"debug"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid revert duration",
+			token:          "secret-token",
+			body:           `{"level":"debug","revertAfter":"-1m"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:            "Component level",
+			token:           "secret-token",
+			body:            `{"component":"api","level":"debug","revertAfter":"10m"}`,
+			expectedStatus:  http.StatusOK,
+			expectedLevels:  []string{"info", "debug"},
+			expectedReverts: []bool{false, true},
+		},
+		{
+			name:            "Global level",
+			token:           "secret-token",
+			body:            `{"level":"warn"}`,
+			expectedStatus:  http.StatusOK,
+			expectedLevels:  []string{"warn", "debug"},
+			expectedReverts: []bool{true, true},
+		},
+	}
+
+	// the cases change the same levels, so they run in order
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/debug/loglevel", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expectedStatus, resp.StatusCode, tt.name)
+
+		if tt.expectedLevels != nil {
+			var logLevels []api.LogLevel
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&logLevels), tt.name)
+			assert.Len(t, logLevels, len(tt.expectedLevels), tt.name)
+			for i, level := range logLevels {
+				assert.Equal(t, tt.expectedLevels[i], level.Level, tt.name)
+				assert.Equal(t, tt.expectedReverts[i], level.RevertAt != nil, tt.name)
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/loglevel", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var logLevels []api.LogLevel
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&logLevels))
+	assert.Equal(t, "", logLevels[0].Component)
+	assert.Equal(t, "api", logLevels[1].Component)
+}
+
+func TestLogLevelDisabled(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	_, levels, err := logging.New(logging.Config{Level: "info", Format: logging.FormatJSON, OutputPath: "stdout"})
+	assert.NoError(t, err)
+
+	app := api.New(
+		zap.NewNop(),
+		getTestMockMetricsCollector(ctrl),
+		mock_provider.NewMockProvider(ctrl),
+		api.WithLogLevels(levels, "", time.Hour),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/loglevel", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
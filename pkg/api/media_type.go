@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	mediaTypeWebhook      = "application/external.dns.webhook+json"
+	mediaTypeVersionParam = "version"
+	protocolVersionLocal  = "protocolVersion"
+)
+
+// protocolVersion is a version of the external-dns webhook protocol.
+type protocolVersion struct {
+	version   string
+	mediaType string
+}
+
+// protocolVersions is the registry of the supported webhook protocol versions. A version added here can be
+// negotiated by the clients, the handlers read it with getProtocolVersion. The first version is used if the client
+// accepts any version.
+var protocolVersions = []*protocolVersion{
+	{version: "1", mediaType: mediaTypeFormat},
+}
+
+// NewMediaTypeMiddleware negotiates the webhook protocol version from the Accept header as described by the
+// external-dns webhook specification. Requests accepting no supported version are answered with 406, request
+// bodies of an unsupported media type with 415.
+func NewMediaTypeMiddleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ctx.Vary(fiber.HeaderContentType, fiber.HeaderAccept)
+
+		contentType := ctx.Get(fiber.HeaderContentType)
+		if contentType != "" && len(ctx.Body()) > 0 {
+			if _, found := findProtocolVersion(contentType); !found {
+				return ctx.Status(fiber.StatusUnsupportedMediaType).JSON(Message{
+					Message: fmt.Sprintf("unsupported content type %q, supported: %s", contentType, supportedMediaTypes()),
+				})
+			}
+		}
+
+		version, found := negotiateProtocolVersion(ctx.Get(fiber.HeaderAccept))
+		if !found {
+			return ctx.Status(fiber.StatusNotAcceptable).JSON(Message{
+				Message: fmt.Sprintf(
+					"none of the accepted media types %q is supported, supported: %s",
+					ctx.Get(fiber.HeaderAccept), supportedMediaTypes(),
+				),
+			})
+		}
+
+		ctx.Locals(protocolVersionLocal, version)
+
+		return ctx.Next()
+	}
+}
+
+// getProtocolVersion returns the protocol version negotiated by the media type middleware.
+func getProtocolVersion(ctx *fiber.Ctx) *protocolVersion {
+	version, ok := ctx.Locals(protocolVersionLocal).(*protocolVersion)
+	if !ok {
+		return protocolVersions[0]
+	}
+
+	return version
+}
+
+// sendJSON sends the body as JSON with the media type of the negotiated protocol version.
+func sendJSON(ctx *fiber.Ctx, body any) error {
+	return ctx.JSON(body, getProtocolVersion(ctx).mediaType)
+}
+
+type acceptedMediaType struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateProtocolVersion returns the supported protocol version preferred by the Accept header.
+func negotiateProtocolVersion(accept string) (*protocolVersion, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return protocolVersions[0], true
+	}
+
+	var accepted []acceptedMediaType
+	for _, mediaRange := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(mediaRange)
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := params["q"]; found {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality <= 0 {
+				continue
+			}
+		}
+
+		accepted = append(accepted, acceptedMediaType{mediaType: mediaRange, quality: quality})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].quality > accepted[j].quality
+	})
+
+	for _, mediaType := range accepted {
+		if version, found := findProtocolVersion(mediaType.mediaType); found {
+			return version, true
+		}
+	}
+
+	return nil, false
+}
+
+// findProtocolVersion returns the protocol version of a media type. Wildcards and plain JSON match the default
+// version.
+func findProtocolVersion(mediaType string) (*protocolVersion, bool) {
+	base, params, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return nil, false
+	}
+
+	switch base {
+	case "*/*", "application/*", fiber.MIMEApplicationJSON:
+		return protocolVersions[0], true
+	case mediaTypeWebhook:
+		version, found := params[mediaTypeVersionParam]
+		if !found {
+			return protocolVersions[0], true
+		}
+
+		for _, protocolVersion := range protocolVersions {
+			if protocolVersion.version == version {
+				return protocolVersion, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func supportedMediaTypes() string {
+	mediaTypes := make([]string, 0, len(protocolVersions))
+	for _, version := range protocolVersions {
+		mediaTypes = append(mediaTypes, version.mediaType)
+	}
+
+	return strings.Join(mediaTypes, ", ")
+}
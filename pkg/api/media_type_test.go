@@ -0,0 +1,119 @@
+package api_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestWebhook_MediaTypeNegotiation(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	tests := []struct {
+		name           string
+		accept         string
+		expectedStatus int
+	}{
+		{
+			name:           "No Accept header",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Version 1",
+			accept:         "application/external.dns.webhook+json;version=1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Media type without version",
+			accept:         "application/external.dns.webhook+json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Plain JSON",
+			accept:         "application/json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Wildcard",
+			accept:         "*/*",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Unsupported version preferred",
+			accept: "application/external.dns.webhook+json;version=2, " +
+				"application/external.dns.webhook+json;version=1;q=0.5",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unsupported version",
+			accept:         "application/external.dns.webhook+json;version=2",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:           "Version 1 refused",
+			accept:         "application/external.dns.webhook+json;version=1;q=0",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+		{
+			name:           "Unsupported media type",
+			accept:         "text/html",
+			expectedStatus: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockProvider := mock_provider.NewMockProvider(ctrl)
+			app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider)
+			mockProvider.EXPECT().Records(gomock.Any()).Return([]*endpoint.Endpoint{}, nil).AnyTimes()
+
+			req := httptest.NewRequest(http.MethodGet, "/records", nil)
+			if tt.accept != "" {
+				req.Header.Set(fiber.HeaderAccept, tt.accept)
+			}
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			assert.Equal(t, "Content-Type, Accept", resp.Header.Get(fiber.HeaderVary))
+
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(
+					t, "application/external.dns.webhook+json;version=1", resp.Header.Get(fiber.HeaderContentType),
+				)
+			}
+		})
+	}
+}
+
+func TestWebhook_MediaTypeOfRequestBody(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	mockProvider := mock_provider.NewMockProvider(ctrl)
+	app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider)
+
+	req := httptest.NewRequest(http.MethodPost, "/records", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set(fiber.HeaderContentType, "application/external.dns.webhook+json;version=2")
+
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+}
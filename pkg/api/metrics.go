@@ -32,13 +32,28 @@ func NewMetricsMiddleware(collector metrics_collector.HttpApiMetrics) fiber.Hand
 		collector.CollectRequest(method, path, status)
 		collector.CollectTotalRequests()
 		collectHttpStatusErrors(status, collector)
-		collector.CollectRequestResponseSize(method, path, float64(len(c.Response().Body())))
 		collector.CollectRequestDuration(method, path, time.Since(started).Seconds())
 
+		// reading the body of a streamed response would buffer it as a whole, so the stream reports its size once
+		// it was written
+		if !c.Response().IsBodyStream() {
+			collector.CollectRequestResponseSize(method, path, float64(len(c.Response().Body())))
+		} else if stream, ok := c.Response().BodyStream().(sizeReportingStream); ok {
+			path = strings.Clone(path)
+			stream.onWritten(func(size int) {
+				collector.CollectRequestResponseSize(method, path, float64(size))
+			})
+		}
+
 		return nil
 	}
 }
 
+// sizeReportingStream is implemented by response body streams that report their size once they were written.
+type sizeReportingStream interface {
+	onWritten(report func(size int))
+}
+
 func collectHttpStatusErrors(status int, collector metrics_collector.HttpApiMetrics) {
 	if status >= 400 && status < 500 {
 		collector.Collect400TotalRequests()
@@ -57,6 +57,20 @@ func (mr *MockApiMockRecorder) Listen(port any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Listen", reflect.TypeOf((*MockApi)(nil).Listen), port)
 }
 
+// Shutdown mocks base method.
+func (m *MockApi) Shutdown() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Shutdown")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockApiMockRecorder) Shutdown() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockApi)(nil).Shutdown))
+}
+
 // Test mocks base method.
 func (m *MockApi) Test(req *http.Request, msTimeout ...int) (*http.Response, error) {
 	m.ctrl.T.Helper()
@@ -1,13 +1,45 @@
 package api
 
+import "context"
+
 const (
 	mediaTypeFormat      = "application/external.dns.webhook+json;version=1"
 	contentTypeHeader    = "Content-Type"
 	contentTypePlaintext = "text/plain"
-	varyHeader           = "Vary"
+	blockedChangesHeader = "X-External-DNS-Blocked"
 	logFieldError        = "err"
 )
 
 type Message struct {
 	Message string `json:"message"`
 }
+
+// BlockedChangesMessage is returned when the provider refused to apply a batch of changes.
+type BlockedChangesMessage struct {
+	Message string   `json:"message"`
+	Blocked []string `json:"blocked"`
+}
+
+// blockedChangesError is implemented by provider errors that list changes refused by the provider safety guards.
+type blockedChangesError interface {
+	error
+	BlockedChanges() []string
+}
+
+// refusedBatchError is implemented by provider errors refusing a whole batch of changes until it is within the limits
+// of the provider safety guards.
+type refusedBatchError interface {
+	error
+	RefusedChanges() []string
+}
+
+// undoneChangesError is implemented by provider errors that list changes left undone on shutdown.
+type undoneChangesError interface {
+	error
+	UndoneChanges() []string
+}
+
+// drainer is implemented by providers that finish or stop their running changes on shutdown.
+type drainer interface {
+	Drain(ctx context.Context) error
+}
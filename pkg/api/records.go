@@ -1,23 +1,100 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/gofiber/fiber/v2"
 	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
 )
 
-func (w webhook) Records(ctx *fiber.Ctx) error {
-	records, err := w.provider.Records(ctx.UserContext())
+// zoneRecordsStreamer is implemented by providers that pass the records zone by zone as soon as they are read, so
+// the response is written without holding the records of all zones in memory.
+type zoneRecordsStreamer interface {
+	StreamRecordsPerZone(ctx context.Context, emit func(endpoints []*endpoint.Endpoint) error) error
+}
+
+// recordsStreamer passes the records of providers without streaming support as a single group.
+type recordsStreamer struct {
+	provider provider.Provider
+}
+
+func (s recordsStreamer) StreamRecordsPerZone(
+	ctx context.Context,
+	emit func(endpoints []*endpoint.Endpoint) error,
+) error {
+	records, err := s.provider.Records(ctx)
 	if err != nil {
+		return err
+	}
+
+	return emit(records)
+}
+
+// Records streams the records of the provider as a single JSON array with chunked transfer encoding. The response
+// starts once the first zone was read, so failures before are answered with 500, which external-dns retries. A
+// failure after aborts the response.
+func (w webhook) Records(ctx *fiber.Ctx) error {
+	streamer, ok := w.provider.(zoneRecordsStreamer)
+	if !ok {
+		streamer = recordsStreamer{provider: w.provider}
+	}
+
+	streamCtx, cancel := detachContext(ctx.UserContext())
+	reader, writer := io.Pipe()
+
+	// started receives nil once the first zone was read or the error if the stream failed before
+	started := make(chan error, 1)
+	go func() {
+		encoder := newEndpointsEncoder(writer)
+		first := true
+		err := streamer.StreamRecordsPerZone(streamCtx, func(endpoints []*endpoint.Endpoint) error {
+			if first {
+				first = false
+				started <- nil
+			}
+
+			// the records are only formatted if debug logging is enabled
+			if entry := w.logger.Check(zap.DebugLevel, "returning records"); entry != nil {
+				entry.Write(zap.String("records", fmt.Sprintf("%v", endpoints)))
+			}
+
+			return encoder.encode(endpoints)
+		})
+		if first {
+			started <- err
+			if err != nil {
+				writer.CloseWithError(err)
+
+				return
+			}
+		}
+		if err == nil {
+			err = encoder.close()
+		} else {
+			w.logger.Error("Error streaming records", zap.String(logFieldError, err.Error()))
+		}
+
+		writer.CloseWithError(err)
+	}()
+
+	if err := <-started; err != nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			// the stream context has the deadline of the request context, which is answered with 504 once it expired
+			<-ctx.UserContext().Done()
+		}
 		w.logger.Error("Error getting records", zap.String(logFieldError, err.Error()))
 
 		return ctx.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	w.logger.Debug("returning records", zap.String("records", fmt.Sprintf("%v", records)))
-
-	ctx.Response().Header.Set(varyHeader, contentTypeHeader)
+	ctx.Response().Header.SetContentType(getProtocolVersion(ctx).mediaType)
+	ctx.Response().SetBodyStream(&endpointsStream{reader: reader, cancel: cancel}, -1)
 
-	return ctx.JSON(records)
+	return nil
 }
@@ -1,14 +1,20 @@
 package api_test
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
 	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
 	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	mock_metrics_collector "github.com/selectel/external-dns-selectel-webhook/pkg/metrics/mock"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
@@ -68,3 +74,160 @@ func TestWebhook_Records(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, respErr.StatusCode)
 	})
 }
+
+// streamingProvider emits the given records zone by zone and fails with the given error after them.
+type streamingProvider struct {
+	*mock_provider.MockProvider
+	records [][]*endpoint.Endpoint
+	err     error
+}
+
+func (p *streamingProvider) StreamRecordsPerZone(
+	ctx context.Context,
+	emit func(endpoints []*endpoint.Endpoint) error,
+) error {
+	for _, endpoints := range p.records {
+		if err := emit(endpoints); err != nil {
+			return err
+		}
+	}
+
+	return p.err
+}
+
+func TestWebhook_StreamRecords(t *testing.T) {
+	t.Parallel()
+
+	// more endpoints than buffered at once
+	large := make([]*endpoint.Endpoint, 0, 1200)
+	for i := range cap(large) {
+		large = append(large, endpoint.NewEndpointWithTTL(
+			fmt.Sprintf("www%d.large.com", i), "A", 300, "127.0.0.1",
+		))
+	}
+
+	tests := []struct {
+		name    string
+		records [][]*endpoint.Endpoint
+	}{
+		{
+			name: "No zones",
+		},
+		{
+			name:    "Empty zones",
+			records: [][]*endpoint.Endpoint{{}, nil},
+		},
+		{
+			name: "Several zones",
+			records: [][]*endpoint.Endpoint{
+				{endpoint.NewEndpoint("a.first.com", "A", "1.1.1.1")},
+				{},
+				large,
+				{
+					endpoint.NewEndpoint("a.second.com", "TXT", `"text"`),
+					endpoint.NewEndpoint("b.second.com", "CNAME", "a.second.com"),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			// the size of the streamed response is reported once it was written
+			sizes := make(chan float64, 1)
+			metricsCollector := mock_metrics_collector.NewMockHttpApiMetrics(ctrl)
+			metricsCollector.EXPECT().CollectRequest(http.MethodGet, "/records", http.StatusOK)
+			metricsCollector.EXPECT().CollectTotalRequests()
+			metricsCollector.EXPECT().CollectRequestDuration(http.MethodGet, "/records", gomock.Any())
+			metricsCollector.EXPECT().CollectRequestResponseSize(http.MethodGet, "/records", gomock.Any()).
+				Do(func(method, path string, size float64) {
+					sizes <- size
+				})
+
+			provider := &streamingProvider{MockProvider: mock_provider.NewMockProvider(ctrl), records: tt.records}
+			app := api.New(zap.NewNop(), metricsCollector, provider)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil), -1)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "application/external.dns.webhook+json;version=1", resp.Header.Get(fiber.HeaderContentType))
+
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+
+			// a single array like the one of Records
+			expected, err := json.Marshal(append([]*endpoint.Endpoint{}, slices.Concat(tt.records...)...))
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(expected), string(body))
+
+			select {
+			case size := <-sizes:
+				assert.Equal(t, float64(len(body)), size)
+			case <-time.After(time.Second):
+				t.Error("size of the response not reported")
+			}
+		})
+	}
+}
+
+func TestWebhook_StreamRecordsError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		records [][]*endpoint.Endpoint
+	}{
+		{
+			name: "Error before the first zone",
+		},
+		{
+			name:    "Error after the first zone",
+			records: [][]*endpoint.Endpoint{{endpoint.NewEndpoint("a.first.com", "A", "1.1.1.1")}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			provider := &streamingProvider{
+				MockProvider: mock_provider.NewMockProvider(ctrl),
+				records:      tt.records,
+				err:          fmt.Errorf("zone failed"),
+			}
+			metricsCollector := mock_metrics_collector.NewMockHttpApiMetrics(ctrl)
+			metricsCollector.EXPECT().CollectRequest(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			metricsCollector.EXPECT().CollectTotalRequests().AnyTimes()
+			metricsCollector.EXPECT().CollectRequestDuration(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			metricsCollector.EXPECT().CollectRequestResponseSize(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			metricsCollector.EXPECT().Collect500TotalRequests().AnyTimes()
+			app := api.New(zap.NewNop(), metricsCollector, provider)
+
+			resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil), -1)
+			if tt.records == nil {
+				// external-dns retries on 500
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+				return
+			}
+
+			// the response already started, so it is aborted and never a complete JSON array
+			if err == nil {
+				body, readErr := io.ReadAll(resp.Body)
+				assert.Error(t, readErr)
+				assert.False(t, json.Valid(body))
+			}
+		})
+	}
+}
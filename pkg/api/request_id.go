@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+)
+
+// NewRequestIDMiddleware passes the request id sent by external-dns, if there is one, to the provider calls.
+func NewRequestIDMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if id := c.Get(requestid.Header); id != "" {
+			c.SetUserContext(requestid.NewContext(c.UserContext(), id))
+		}
+
+		return c.Next()
+	}
+}
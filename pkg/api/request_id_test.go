@@ -0,0 +1,58 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/requestid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	tests := []struct {
+		name       string
+		header     string
+		expectedID string
+	}{
+		{name: "Request id is sent", header: "abc-123", expectedID: "abc-123"},
+		{name: "Request id is missing", header: "", expectedID: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockProvider := mock_provider.NewMockProvider(ctrl)
+			app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider)
+			mockProvider.EXPECT().Records(gomock.Any()).DoAndReturn(
+				func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+					assert.Equal(t, tt.expectedID, requestid.FromContext(ctx))
+
+					return nil, nil
+				},
+			).Times(1)
+
+			req := httptest.NewRequest(http.MethodGet, "/records", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewTimeoutMiddleware sets a deadline of the given timeout on the context passed to the provider calls. A failed
+// request that exceeded the deadline is answered with 504 Gateway Timeout. The deadline is disabled if the timeout
+// is not positive.
+func NewTimeoutMiddleware(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+			(err == nil && c.Response().StatusCode() < fiber.StatusInternalServerError) {
+			return err
+		}
+
+		c.Response().ResetBody()
+
+		return sendJSON(c.Status(fiber.StatusGatewayTimeout), Message{
+			Message: fmt.Sprintf("request deadline of %s exceeded", timeout),
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/api"
+	mock_provider "github.com/selectel/external-dns-selectel-webhook/pkg/api/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestWebhook_RequestTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	waitForDeadline := func(ctx context.Context) error {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	t.Run("Records exceeding the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		app := api.New(
+			zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider, api.WithRequestTimeout(50*time.Millisecond),
+		)
+		mockProvider.EXPECT().Records(gomock.Any()).DoAndReturn(
+			func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+				return nil, waitForDeadline(ctx)
+			},
+		).Times(1)
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+		var message api.Message
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&message))
+		assert.Equal(t, "request deadline of 50ms exceeded", message.Message)
+	})
+
+	t.Run("ApplyChanges exceeding the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		app := api.New(
+			zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider, api.WithRequestTimeout(50*time.Millisecond),
+		)
+		mockProvider.EXPECT().ApplyChanges(gomock.Any(), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, _ *plan.Changes) error {
+				return waitForDeadline(ctx)
+			},
+		).Times(1)
+
+		req := httptest.NewRequest(http.MethodPost, "/records", bytes.NewReader([]byte(`{}`)))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	})
+
+	t.Run("Records within the deadline", func(t *testing.T) {
+		t.Parallel()
+
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider, api.WithRequestTimeout(time.Minute))
+		mockProvider.EXPECT().Records(gomock.Any()).DoAndReturn(
+			func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+				_, found := ctx.Deadline()
+				assert.True(t, found)
+
+				return []*endpoint.Endpoint{}, nil
+			},
+		).Times(1)
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("No deadline by default", func(t *testing.T) {
+		t.Parallel()
+
+		mockProvider := mock_provider.NewMockProvider(ctrl)
+		app := api.New(zap.NewNop(), getTestMockMetricsCollector(ctrl), mockProvider)
+		mockProvider.EXPECT().Records(gomock.Any()).DoAndReturn(
+			func(ctx context.Context) ([]*endpoint.Endpoint, error) {
+				_, found := ctx.Deadline()
+				assert.False(t, found)
+
+				return []*endpoint.Endpoint{}, nil
+			},
+		).Times(1)
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/records", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
@@ -0,0 +1,529 @@
+package fakeselectel
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+)
+
+const (
+	defaultLimit = 1000
+
+	minTTL = 60
+	maxTTL = 604800
+)
+
+type zoneCreateForm struct {
+	Name string `json:"name"`
+}
+
+type zoneStateForm struct {
+	Disabled bool `json:"disabled"`
+}
+
+type rrSetCreateForm struct {
+	Name      string               `json:"name"`
+	TTL       int                  `json:"ttl"`
+	Type      domains.RecordType   `json:"type"`
+	Records   []domains.RecordItem `json:"records"`
+	Comment   string               `json:"comment"`
+	ManagedBy string               `json:"managed_by"`
+}
+
+type rrSetUpdateForm struct {
+	TTL       int                  `json:"ttl"`
+	Records   []domains.RecordItem `json:"records"`
+	Comment   string               `json:"comment"`
+	ManagedBy string               `json:"managed_by"`
+}
+
+// domainsHandler returns the handler of the DNS API. Paths are relative to DomainsPath.
+func (s *Server) domainsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /zones", s.listZones)
+	mux.HandleFunc("POST /zones", s.createZone)
+	mux.HandleFunc("GET /zones/{zoneID}", s.getZone)
+	mux.HandleFunc("DELETE /zones/{zoneID}", s.deleteZone)
+	mux.HandleFunc("PATCH /zones/{zoneID}/state", s.updateZoneState)
+	mux.HandleFunc("GET /zones/{zoneID}/rrset", s.listRRSets)
+	mux.HandleFunc("POST /zones/{zoneID}/rrset", s.createRRSet)
+	mux.HandleFunc("GET /zones/{zoneID}/rrset/{rrSetID}", s.getRRSet)
+	mux.HandleFunc("PATCH /zones/{zoneID}/rrset/{rrSetID}", s.updateRRSet)
+	mux.HandleFunc("DELETE /zones/{zoneID}/rrset/{rrSetID}", s.deleteRRSet)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.throttled() {
+			writeError(w, newAPIError(http.StatusTooManyRequests, "rate limit exceeded"))
+
+			return
+		}
+
+		if !s.authorized(r) {
+			writeError(w, newAPIError(http.StatusUnauthorized, "invalid or missing %s header", authTokenHeader))
+
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// throttled counts the request and reports whether it has to be answered with 429.
+func (s *Server) throttled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.rateLimited > 0 {
+		s.rateLimited--
+
+		return true
+	}
+
+	return s.config.RateLimitEvery > 0 && s.requests%s.config.RateLimitEvery == 0
+}
+
+func (s *Server) listZones(w http.ResponseWriter, r *http.Request) {
+	filters := r.URL.Query()["filter"]
+
+	s.mu.Lock()
+	var zones []*domains.Zone
+	for _, state := range s.sortedZones() {
+		if matchesAny(state.zone.Name, filters) {
+			copied := *state.zone
+			zones = append(zones, &copied)
+		}
+	}
+	s.mu.Unlock()
+
+	list, apiErr := paginate(r, zones)
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &domains.List[domains.Zone]{
+		Count:      len(zones),
+		NextOffset: list.nextOffset,
+		Items:      list.items,
+	})
+}
+
+func (s *Server) createZone(w http.ResponseWriter, r *http.Request) {
+	var form zoneCreateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid zone: %v", err))
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone, apiErr := s.addZone(form.Name)
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, zone)
+}
+
+func (s *Server) getZone(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, apiErr := s.zone(r.PathValue("zoneID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, state.zone)
+}
+
+func (s *Server) deleteZone(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, apiErr := s.zone(r.PathValue("zoneID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	delete(s.zones, state.zone.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) updateZoneState(w http.ResponseWriter, r *http.Request) {
+	var form zoneStateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid zone state: %v", err))
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, apiErr := s.zone(r.PathValue("zoneID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	state.zone.Disabled = form.Disabled
+	state.zone.UpdatedAt = time.Now().UTC()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRRSets(w http.ResponseWriter, r *http.Request) {
+	names := r.URL.Query()["name"]
+
+	s.mu.Lock()
+	state, apiErr := s.zone(r.PathValue("zoneID"))
+	if apiErr != nil {
+		s.mu.Unlock()
+		writeError(w, apiErr)
+
+		return
+	}
+
+	var rrSets []*domains.RRSet
+	for _, rrSet := range state.rrSets {
+		if len(names) == 0 || containsName(names, rrSet.Name) {
+			rrSets = append(rrSets, copyRRSet(rrSet))
+		}
+	}
+	s.mu.Unlock()
+
+	list, apiErr := paginate(r, rrSets)
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &domains.List[domains.RRSet]{
+		Count:      len(rrSets),
+		NextOffset: list.nextOffset,
+		Items:      list.items,
+	})
+}
+
+func (s *Server) createRRSet(w http.ResponseWriter, r *http.Request) {
+	var form rrSetCreateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid rrset: %v", err))
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rrSet, apiErr := s.addRRSet(r.PathValue("zoneID"), &domains.RRSet{
+		Name:      form.Name,
+		TTL:       form.TTL,
+		Type:      form.Type,
+		Records:   form.Records,
+		Comment:   form.Comment,
+		ManagedBy: form.ManagedBy,
+	})
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rrSet)
+}
+
+func (s *Server) getRRSet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, rrSet, apiErr := s.rrSet(r.PathValue("zoneID"), r.PathValue("rrSetID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rrSet)
+}
+
+func (s *Server) updateRRSet(w http.ResponseWriter, r *http.Request) {
+	var form rrSetUpdateForm
+	if err := json.NewDecoder(r.Body).Decode(&form); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid rrset: %v", err))
+
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, rrSet, apiErr := s.rrSet(r.PathValue("zoneID"), r.PathValue("rrSetID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	apiErr = validateRRSet(rrSet.Type, form.TTL, form.Records)
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	rrSet.TTL = form.TTL
+	rrSet.Records = append([]domains.RecordItem(nil), form.Records...)
+	rrSet.Comment = form.Comment
+	rrSet.ManagedBy = form.ManagedBy
+	state.zone.UpdatedAt = time.Now().UTC()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteRRSet(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, rrSet, apiErr := s.rrSet(r.PathValue("zoneID"), r.PathValue("rrSetID"))
+	if apiErr != nil {
+		writeError(w, apiErr)
+
+		return
+	}
+
+	for i := range state.rrSets {
+		if state.rrSets[i] == rrSet {
+			state.rrSets = append(state.rrSets[:i], state.rrSets[i+1:]...)
+
+			break
+		}
+	}
+	state.zone.UpdatedAt = time.Now().UTC()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// addZone creates a zone. The caller must hold s.mu.
+func (s *Server) addZone(name string) (*domains.Zone, *apiError) {
+	name = normalizeName(name)
+	if name == "." {
+		return nil, newAPIError(http.StatusBadRequest, "zone name is required")
+	}
+
+	for _, state := range s.zones {
+		if state.zone.Name == name {
+			return nil, newAPIError(http.StatusConflict, "zone %s already exists", name)
+		}
+	}
+
+	now := time.Now().UTC()
+	zone := &domains.Zone{
+		ID:        s.nextID(),
+		Name:      name,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.zones[zone.ID] = &zoneState{zone: zone}
+
+	return zone, nil
+}
+
+// addRRSet creates a record set in the zone. The caller must hold s.mu.
+func (s *Server) addRRSet(zoneID string, rrSet *domains.RRSet) (*domains.RRSet, *apiError) {
+	state, apiErr := s.zone(zoneID)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	name := normalizeName(rrSet.Name)
+	if name != state.zone.Name && !strings.HasSuffix(name, "."+state.zone.Name) {
+		return nil, newAPIError(http.StatusBadRequest, "rrset %s is not within zone %s", name, state.zone.Name)
+	}
+
+	apiErr = validateRRSet(rrSet.Type, rrSet.TTL, rrSet.Records)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	for _, existing := range state.rrSets {
+		if existing.Name != name {
+			continue
+		}
+
+		if existing.Type == rrSet.Type || existing.Type == domains.CNAME || rrSet.Type == domains.CNAME {
+			return nil, newAPIError(
+				http.StatusConflict, "rrset %s %s conflicts with existing %s", name, rrSet.Type, existing.Type,
+			)
+		}
+	}
+
+	created := copyRRSet(rrSet)
+	created.ID = s.nextID()
+	created.ZoneID = state.zone.ID
+	created.Name = name
+	state.rrSets = append(state.rrSets, created)
+	state.zone.UpdatedAt = time.Now().UTC()
+
+	return created, nil
+}
+
+// zone returns the zone with the given id. The caller must hold s.mu.
+func (s *Server) zone(zoneID string) (*zoneState, *apiError) {
+	state, found := s.zones[zoneID]
+	if !found {
+		return nil, newAPIError(http.StatusNotFound, "zone %s not found", zoneID)
+	}
+
+	return state, nil
+}
+
+// rrSet returns the record set with the given id. The caller must hold s.mu.
+func (s *Server) rrSet(zoneID, rrSetID string) (*zoneState, *domains.RRSet, *apiError) {
+	state, apiErr := s.zone(zoneID)
+	if apiErr != nil {
+		return nil, nil, apiErr
+	}
+
+	for _, rrSet := range state.rrSets {
+		if rrSet.ID == rrSetID {
+			return state, rrSet, nil
+		}
+	}
+
+	return nil, nil, newAPIError(http.StatusNotFound, "rrset %s not found", rrSetID)
+}
+
+// validateRRSet checks the TTL and the records of a record set the same way the Selectel DNS API does.
+func validateRRSet(recordType domains.RecordType, ttl int, records []domains.RecordItem) *apiError {
+	if ttl < minTTL || ttl > maxTTL {
+		return newAPIError(http.StatusBadRequest, "ttl must be between %d and %d", minTTL, maxTTL)
+	}
+
+	if len(records) == 0 {
+		return newAPIError(http.StatusBadRequest, "at least one record is required")
+	}
+
+	for _, record := range records {
+		ip := net.ParseIP(record.Content)
+
+		switch {
+		case record.Content == "":
+			return newAPIError(http.StatusBadRequest, "record content is required")
+		case recordType == domains.A && (ip == nil || ip.To4() == nil):
+			return newAPIError(http.StatusBadRequest, "invalid A record %q", record.Content)
+		case recordType == domains.AAAA && (ip == nil || ip.To4() != nil):
+			return newAPIError(http.StatusBadRequest, "invalid AAAA record %q", record.Content)
+		case recordType == domains.SOA:
+			return newAPIError(http.StatusBadRequest, "SOA records are managed by Selectel")
+		}
+	}
+
+	switch recordType {
+	case domains.A, domains.AAAA, domains.ALIAS, domains.CAA, domains.CNAME, domains.MX, domains.NS,
+		domains.SRV, domains.SSHFP, domains.TXT:
+		return nil
+	default:
+		return newAPIError(http.StatusBadRequest, "unsupported record type %q", recordType)
+	}
+}
+
+type page[T any] struct {
+	items      []*T
+	nextOffset int
+}
+
+// paginate returns the page of items selected by the limit and offset query parameters.
+func paginate[T any](r *http.Request, items []*T) (*page[T], *apiError) {
+	limit, apiErr := queryInt(r, "limit", defaultLimit)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	offset, apiErr := queryInt(r, "offset", 0)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+
+	result := &page[T]{items: []*T{}}
+	if offset >= len(items) {
+		return result, nil
+	}
+
+	end := offset + limit
+	if end < len(items) {
+		result.nextOffset = end
+	} else {
+		end = len(items)
+	}
+	result.items = items[offset:end]
+
+	return result, nil
+}
+
+func queryInt(r *http.Request, key string, defaultValue int) (int, *apiError) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		return 0, newAPIError(http.StatusBadRequest, "invalid %s %q", key, value)
+	}
+
+	return result, nil
+}
+
+// normalizeName returns the lowercase name with a trailing dot as the Selectel DNS API stores it.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, ".")) + "."
+}
+
+// matchesAny checks if the zone name contains one of the filters. Any name matches if there are no filters.
+func matchesAny(name string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	for _, filter := range filters {
+		if strings.Contains(name, strings.ToLower(filter)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsName checks if the names contain the record set name.
+func containsName(names []string, name string) bool {
+	for _, candidate := range names {
+		if normalizeName(candidate) == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,81 @@
+package fakeselectel
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+const tokenLifetime = 24 * time.Hour
+
+type tokenRequest struct {
+	Auth struct {
+		Identity struct {
+			Password struct {
+				User struct {
+					Name     string `json:"name"`
+					Password string `json:"password"`
+				} `json:"user"`
+			} `json:"password"`
+		} `json:"identity"`
+	} `json:"auth"`
+}
+
+type tokenResponse struct {
+	Token struct {
+		ExpiresAt time.Time `json:"expires_at"`
+		IssuedAt  time.Time `json:"issued_at"`
+		Methods   []string  `json:"methods"`
+		Catalog   []any     `json:"catalog"`
+	} `json:"token"`
+}
+
+// createToken issues a token for the password credentials of the request, like POST /v3/auth/tokens of Keystone.
+func (s *Server) createToken(w http.ResponseWriter, r *http.Request) {
+	var request tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, newAPIError(http.StatusBadRequest, "invalid token request: %v", err))
+
+		return
+	}
+
+	user := request.Auth.Identity.Password.User
+	if (s.config.Username != "" && user.Name != s.config.Username) ||
+		(s.config.Password != "" && user.Password != s.config.Password) {
+		writeError(w, newAPIError(http.StatusUnauthorized, "the request you have made requires authentication"))
+
+		return
+	}
+
+	s.mu.Lock()
+	token := s.nextID()
+	s.tokens[token] = struct{}{}
+	s.mu.Unlock()
+
+	var response tokenResponse
+	response.Token.IssuedAt = time.Now().UTC()
+	response.Token.ExpiresAt = response.Token.IssuedAt.Add(tokenLifetime)
+	response.Token.Methods = []string{"password"}
+	response.Token.Catalog = []any{}
+
+	w.Header().Set("X-Subject-Token", token)
+	writeJSON(w, http.StatusCreated, &response)
+}
+
+// AddToken makes the fake DNS API accept the given token, e.g. the one returned by a mocked Keystone provider.
+func (s *Server) AddToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[token] = struct{}{}
+}
+
+// authorized checks that the request carries a token issued by createToken.
+func (s *Server) authorized(r *http.Request) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.tokens[r.Header.Get(authTokenHeader)]
+
+	return found
+}
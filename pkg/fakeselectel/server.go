@@ -0,0 +1,220 @@
+package fakeselectel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+)
+
+const (
+	// KeystonePath is the path the fake Keystone identity API is served on. It is the --auth-url of the webhook
+	// together with the address of the server.
+	KeystonePath = "/identity/v3"
+	// DomainsPath is the path the fake Selectel DNS API is served on. It is the --base-url of the webhook
+	// together with the address of the server.
+	DomainsPath = "/domains/v2"
+
+	authTokenHeader = "X-Auth-Token"
+)
+
+// Config is used to configure the fake server.
+type Config struct {
+	// Username and Password are the credentials a token is issued for. Any credentials are accepted if empty.
+	Username string
+	Password string
+	// RateLimitEvery answers every n-th request to the DNS API with 429 Too Many Requests. 0 disables it.
+	RateLimitEvery int
+}
+
+// Server is a stateful in-memory fake of the Selectel DNS API v2 and the Keystone token endpoint.
+// It is safe for concurrent use.
+type Server struct {
+	config  Config
+	handler http.Handler
+
+	mu          sync.Mutex
+	zones       map[string]*zoneState
+	tokens      map[string]struct{}
+	lastID      int
+	requests    int
+	rateLimited int
+}
+
+type zoneState struct {
+	zone   *domains.Zone
+	rrSets []*domains.RRSet
+}
+
+// New returns an empty fake server.
+func New(config Config) *Server {
+	s := &Server{
+		config: config,
+		zones:  make(map[string]*zoneState),
+		tokens: make(map[string]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+KeystonePath+"/auth/tokens", s.createToken)
+	mux.Handle(DomainsPath+"/", http.StripPrefix(DomainsPath, s.domainsHandler()))
+	s.handler = mux
+
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// AddZone creates a zone with the given name and returns it.
+func (s *Server) AddZone(name string) (*domains.Zone, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	zone, err := s.addZone(name)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *zone
+
+	return &copied, nil
+}
+
+// SetZoneDelegated sets the result of the last delegation check of the zone.
+func (s *Server) SetZoneDelegated(zoneID string, delegated bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, apiErr := s.zone(zoneID)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	now := time.Now().UTC()
+	state.zone.LastCheckStatus = delegated
+	state.zone.DelegationCheckedAt = now
+	if delegated {
+		state.zone.LastDelegatedAt = now
+	}
+
+	return nil
+}
+
+// AddRRSet creates a record set in the zone and returns it.
+func (s *Server) AddRRSet(zoneID string, rrSet domains.RRSet) (*domains.RRSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created, err := s.addRRSet(zoneID, &rrSet)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyRRSet(created), nil
+}
+
+// Zones returns a copy of all zones ordered by name.
+func (s *Server) Zones() []*domains.Zone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*domains.Zone, 0, len(s.zones))
+	for _, state := range s.sortedZones() {
+		copied := *state.zone
+		result = append(result, &copied)
+	}
+
+	return result
+}
+
+// RRSets returns a copy of all record sets of the zone ordered by name and type.
+func (s *Server) RRSets(zoneID string) []*domains.RRSet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, found := s.zones[zoneID]
+	if !found {
+		return nil
+	}
+
+	result := make([]*domains.RRSet, 0, len(state.rrSets))
+	for _, rrSet := range state.rrSets {
+		result = append(result, copyRRSet(rrSet))
+	}
+
+	return result
+}
+
+// InjectRateLimit answers the next n requests to the DNS API with 429 Too Many Requests.
+func (s *Server) InjectRateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rateLimited += n
+}
+
+// nextID returns a new unique id in the UUID format.
+func (s *Server) nextID() string {
+	s.lastID++
+
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", s.lastID)
+}
+
+func (s *Server) sortedZones() []*zoneState {
+	result := make([]*zoneState, 0, len(s.zones))
+	for _, state := range s.zones {
+		result = append(result, state)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].zone.Name < result[j].zone.Name
+	})
+
+	return result
+}
+
+// apiError is an error response in the format of the Selectel DNS API.
+type apiError struct {
+	code        int
+	message     string
+	description string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.code, e.message, e.description)
+}
+
+func newAPIError(code int, description string, args ...any) *apiError {
+	return &apiError{
+		code:        code,
+		message:     strings.ToLower(strings.ReplaceAll(http.StatusText(code), " ", "_")),
+		description: fmt.Sprintf(description, args...),
+	}
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, err.code, &domains.BadResponseError{
+		ErrorMsg:    err.message,
+		Description: err.description,
+		Code:        err.code,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func copyRRSet(rrSet *domains.RRSet) *domains.RRSet {
+	copied := *rrSet
+	copied.Records = append([]domains.RecordItem(nil), rrSet.Records...)
+
+	return &copied
+}
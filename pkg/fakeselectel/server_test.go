@@ -0,0 +1,249 @@
+package fakeselectel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	domains "github.com/selectel/domains-go/pkg/v2"
+	"github.com/selectel/external-dns-selectel-webhook/pkg/keystone"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	fake := New(Config{Username: "user", Password: "secret"})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := getClient(t, server.URL)
+
+	zone, err := fake.AddZone("Example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com.", zone.Name)
+
+	_, err = fake.AddZone("example.org.")
+	assert.NoError(t, err)
+
+	t.Run("Zones", func(t *testing.T) {
+		zones, err := client.ListZones(ctx, &map[string]string{"filter": "example.com"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, zones.GetCount())
+		assert.Equal(t, zone.ID, zones.GetItems()[0].ID)
+
+		_, err = client.CreateZone(ctx, &domains.Zone{Name: "example.org"})
+		assertCode(t, http.StatusConflict, err)
+
+		_, err = client.GetZone(ctx, "unknown", nil)
+		assert.ErrorIs(t, err, domains.ErrNotFound)
+	})
+
+	t.Run("Record set lifecycle", func(t *testing.T) {
+		created, err := client.CreateRRSet(ctx, zone.ID, &domains.RRSet{
+			Name:    "www.example.com",
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "192.0.2.1"}},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "www.example.com.", created.Name)
+		assert.Equal(t, zone.ID, created.ZoneID)
+
+		_, err = client.CreateRRSet(ctx, zone.ID, &domains.RRSet{
+			Name:    "www.example.com.",
+			Type:    domains.CNAME,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "example.com."}},
+		})
+		assertCode(t, http.StatusConflict, err)
+
+		err = client.UpdateRRSet(ctx, zone.ID, created.ID, &domains.RRSet{
+			TTL:     60,
+			Records: []domains.RecordItem{{Content: "192.0.2.2"}},
+		})
+		assert.NoError(t, err)
+
+		rrSets, err := client.ListRRSets(ctx, zone.ID, &map[string]string{"name": "WWW.example.com."})
+		assert.NoError(t, err)
+		assert.Len(t, rrSets.GetItems(), 1)
+		assert.Equal(t, 60, rrSets.GetItems()[0].TTL)
+		assert.Equal(t, "192.0.2.2", rrSets.GetItems()[0].Records[0].Content)
+
+		assert.NoError(t, client.DeleteRRSet(ctx, zone.ID, created.ID))
+		assert.Empty(t, fake.RRSets(zone.ID))
+
+		err = client.DeleteRRSet(ctx, zone.ID, created.ID)
+		assert.ErrorIs(t, err, domains.ErrNotFound)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := client.CreateRRSet(ctx, zone.ID, &domains.RRSet{
+			Name:    "www.example.org.",
+			Type:    domains.A,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "192.0.2.1"}},
+		})
+		assertCode(t, http.StatusBadRequest, err)
+
+		_, err = client.CreateRRSet(ctx, zone.ID, &domains.RRSet{
+			Name:    "www.example.com.",
+			Type:    domains.AAAA,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "192.0.2.1"}},
+		})
+		assertCode(t, http.StatusBadRequest, err)
+	})
+}
+
+func TestServerPagination(t *testing.T) {
+	t.Parallel()
+
+	fake := New(Config{})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"a", "b", "c"} {
+		_, err = fake.AddRRSet(zone.ID, domains.RRSet{
+			Name:    name + ".example.com.",
+			Type:    domains.TXT,
+			TTL:     300,
+			Records: []domains.RecordItem{{Content: "\"" + name + "\""}},
+		})
+		assert.NoError(t, err)
+	}
+
+	client := getClient(t, server.URL)
+
+	var names []string
+	offset := "0"
+	for {
+		rrSets, err := client.ListRRSets(context.Background(), zone.ID, &map[string]string{
+			"limit":  "2",
+			"offset": offset,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, rrSets.GetCount())
+
+		for _, rrSet := range rrSets.GetItems() {
+			names = append(names, rrSet.Name)
+		}
+
+		if rrSets.GetNextOffset() == 0 {
+			break
+		}
+		offset = "2"
+	}
+
+	assert.Equal(t, []string{"a.example.com.", "b.example.com.", "c.example.com."}, names)
+}
+
+func TestServerErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Invalid credentials", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(New(Config{Username: "user", Password: "secret"}))
+		defer server.Close()
+
+		keystoneProvider := keystone.NewProvider(zap.NewNop(), keystone.Credentials{
+			IdentityEndpoint: server.URL + KeystonePath,
+			AccountID:        "account",
+			ProjectID:        "project",
+			Username:         "user",
+			Password:         "wrong",
+		}, nil)
+		_, err := keystoneProvider.GetToken(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		t.Parallel()
+
+		server := httptest.NewServer(New(Config{}))
+		defer server.Close()
+
+		client := domains.NewClient(server.URL+DomainsPath, http.DefaultClient, http.Header{})
+		_, err := client.ListZones(context.Background(), nil)
+		assertCode(t, http.StatusUnauthorized, err)
+	})
+
+	t.Run("Rate limit", func(t *testing.T) {
+		t.Parallel()
+
+		fake := New(Config{RateLimitEvery: 3})
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		client := getClient(t, server.URL)
+
+		fake.InjectRateLimit(1)
+		_, err := client.ListZones(context.Background(), nil)
+		assertCode(t, http.StatusTooManyRequests, err)
+
+		_, err = client.ListZones(context.Background(), nil)
+		assert.NoError(t, err)
+
+		_, err = client.ListZones(context.Background(), nil)
+		assertCode(t, http.StatusTooManyRequests, err)
+	})
+}
+
+// getClient returns a DNS client authorized by a token of the fake Keystone.
+func getClient(t *testing.T, url string) domains.DNSClient[domains.Zone, domains.RRSet] {
+	t.Helper()
+
+	keystoneProvider := keystone.NewProvider(zap.NewNop(), keystone.Credentials{
+		IdentityEndpoint: url + KeystonePath,
+		AccountID:        "account",
+		ProjectID:        "project",
+		Username:         "user",
+		Password:         "secret",
+	}, nil)
+	token, err := keystoneProvider.GetToken(context.Background())
+	assert.NoError(t, err)
+
+	headers := http.Header{}
+	headers.Set(authTokenHeader, token)
+
+	return domains.NewClient(url+DomainsPath, http.DefaultClient, headers)
+}
+
+func assertCode(t *testing.T, code int, err error) {
+	t.Helper()
+
+	var badResponseErr *domains.BadResponseError
+	if assert.True(t, errors.As(err, &badResponseErr), "unexpected error %v", err) {
+		assert.Equal(t, code, badResponseErr.Code)
+	}
+}
+
+func TestServerZoneState(t *testing.T) {
+	t.Parallel()
+
+	fake := New(Config{})
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	zone, err := fake.AddZone("example.com")
+	assert.NoError(t, err)
+	assert.False(t, zone.LastCheckStatus)
+
+	client := getClient(t, server.URL)
+	assert.NoError(t, client.UpdateZoneState(context.Background(), zone.ID, true))
+	assert.NoError(t, fake.SetZoneDelegated(zone.ID, true))
+
+	updated, err := client.GetZone(context.Background(), zone.ID, nil)
+	assert.NoError(t, err)
+	assert.True(t, updated.Disabled)
+	assert.True(t, updated.LastCheckStatus)
+}
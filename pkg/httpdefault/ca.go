@@ -0,0 +1,133 @@
+package httpdefault
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// caPool is the certificate pool of a CA file that is reloaded when the file changes, e.g. when a mounted
+// Kubernetes secret is updated. The file is checked lazily on TLS handshakes at most once per interval, so no
+// goroutine is needed.
+type caPool struct {
+	path     string
+	interval time.Duration
+	onReload func(err error)
+
+	mu        sync.Mutex
+	pool      *x509.CertPool
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+func newCAPool(path string, interval time.Duration, onReload func(err error)) (*caPool, error) {
+	p := &caPool{
+		path:     path,
+		interval: interval,
+		onReload: onReload,
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	p.pool, err = loadCAFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.checkedAt = time.Now()
+
+	return p, nil
+}
+
+// get returns the current pool and reloads the CA file first if it changed. The previous pool is kept if the
+// reload fails.
+func (p *caPool) get() *x509.CertPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interval <= 0 || time.Since(p.checkedAt) < p.interval {
+		return p.pool
+	}
+	p.checkedAt = time.Now()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		p.reloaded(fmt.Errorf("failed to read CA file: %w", err))
+
+		return p.pool
+	}
+
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return p.pool
+	}
+
+	pool, err := loadCAFile(p.path)
+	if err != nil {
+		p.reloaded(err)
+
+		return p.pool
+	}
+
+	p.pool = pool
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.reloaded(nil)
+
+	return p.pool
+}
+
+func (p *caPool) reloaded(err error) {
+	if p.onReload != nil {
+		p.onReload(err)
+	}
+}
+
+// verifyConnection verifies the server certificate against the current pool like the default verification of
+// crypto/tls does against RootCAs.
+func (p *caPool) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("server sent no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         p.get(),
+		Intermediates: intermediates,
+	})
+
+	return err
+}
+
+// loadCAFile returns the system certificate pool with the certificates of the PEM file added.
+func loadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in CA file " + path)
+	}
+
+	return pool, nil
+}
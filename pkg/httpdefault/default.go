@@ -1,9 +1,15 @@
 package httpdefault
 
 import (
+	"crypto/tls"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/time/rate"
 )
 
 // UserAgent represents HTTP User-Agent header that should be added to requests to Selectel API.
@@ -23,10 +29,17 @@ const (
 	// maxIdleConns represents the maximum number of idle (keep-alive) connections.
 	maxIdleConns = 100
 
+	// maxIdleConnsPerHost represents the maximum number of idle (keep-alive) connections per host. It should not be
+	// lower than the number of workers, otherwise their connections are not reused.
+	maxIdleConnsPerHost = 10
+
 	// idleConnTimeout represents the maximum amount of time an idle (keep-alive) connection will remain
 	// idle before closing itself.
 	idleConnTimeout = 100
 
+	// caReloadInterval represents the default interval (in seconds) in which the CA file is checked for changes.
+	caReloadInterval = 30
+
 	// tlsHandshakeTimeout represents the default timeout (in seconds) for TLS handshake.
 	tlsHandshakeTimeout = 60
 
@@ -34,23 +47,135 @@ const (
 	expectContinueTimeout = 5
 )
 
+// Options configures the HTTP client for requests to Selectel API.
+type Options struct {
+	// Timeout is the timeout of a single HTTP request.
+	Timeout time.Duration
+	// DialTimeout is the timeout of connection establishments.
+	DialTimeout time.Duration
+	// MaxIdleConns is the maximum number of idle (keep-alive) connections across all hosts.
+	MaxIdleConns int
+	// MaxIdleConnsPerHost is the maximum number of idle (keep-alive) connections per host.
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost limits the number of connections per host. 0 means no limit.
+	MaxConnsPerHost int
+	// EnableHTTP2 allows HTTP/2 if the server supports it.
+	EnableHTTP2 bool
+	// CAFile is a PEM bundle of certificate authorities trusted in addition to the system ones. It is reloaded
+	// when it changes.
+	CAFile string
+	// CAReloadInterval is the interval in which the CA file is checked for changes. 0 disables the reload.
+	CAReloadInterval time.Duration
+	// OnCAReload is called with the result of every reload of the CA file if it is set.
+	OnCAReload func(err error)
+	// HTTPSProxy is the URL of the proxy for all requests. The HTTPS_PROXY and HTTP_PROXY environment variables are
+	// used if it is empty.
+	HTTPSProxy string
+	// NoProxy is a comma-separated list of hosts that are requested without the proxy in the format of the NO_PROXY
+	// environment variable, which is used if it is empty.
+	NoProxy string
+	// InsecureSkipVerify disables the verification of server certificates. It is meant for test endpoints only.
+	InsecureSkipVerify bool
+	// RateLimiter delays the requests exceeding its rate. The limit can be changed at runtime. Requests are not
+	// limited if it is nil.
+	RateLimiter *rate.Limiter
+}
+
+// DefaultOptions returns the options of Client.
+func DefaultOptions() Options {
+	return Options{
+		Timeout:             httpTimeout * time.Second,
+		DialTimeout:         dialTimeout * time.Second,
+		MaxIdleConns:        maxIdleConns,
+		MaxIdleConnsPerHost: maxIdleConnsPerHost,
+		CAReloadInterval:    caReloadInterval * time.Second,
+	}
+}
+
 // Client returns default HTTP client for requests to Selectel API. It does not add User-Agent header, so
 // you should add it by yourself (by default it is UserAgent) or use Headers function.
 func Client() http.Client {
-	return http.Client{
-		Timeout: httpTimeout * time.Second,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   dialTimeout * time.Second,
-				KeepAlive: keepaliveTimeout * time.Second,
-			}).DialContext,
-			MaxIdleConns:          maxIdleConns,
-			IdleConnTimeout:       idleConnTimeout * time.Second,
-			TLSHandshakeTimeout:   tlsHandshakeTimeout * time.Second,
-			ExpectContinueTimeout: expectContinueTimeout * time.Second,
-		},
+	client, err := NewClient(DefaultOptions())
+	if err != nil {
+		// the default options contain nothing that can fail
+		panic(err)
+	}
+
+	return *client
+}
+
+// NewClient returns HTTP client for requests to Selectel API configured by the given options. Like Client, it does
+// not add User-Agent header. The client should be shared, so the connections are reused.
+func NewClient(opts Options) (*http.Client, error) {
+	proxy, err := getProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		//nolint:gosec // it is explicitly enabled for test endpoints
+		InsecureSkipVerify: opts.InsecureSkipVerify,
 	}
+	if opts.CAFile != "" && !opts.InsecureSkipVerify {
+		pool, err := newCAPool(opts.CAFile, opts.CAReloadInterval, opts.OnCAReload)
+		if err != nil {
+			return nil, err
+		}
+		// the default verification is replaced to verify against the reloaded pool
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = pool.verifyConnection
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   opts.DialTimeout,
+			KeepAlive: keepaliveTimeout * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     opts.EnableHTTP2,
+		MaxIdleConns:          opts.MaxIdleConns,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       opts.MaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout * time.Second,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout * time.Second,
+		ExpectContinueTimeout: expectContinueTimeout * time.Second,
+	}
+	if opts.RateLimiter != nil {
+		transport = &rateLimitTransport{next: transport, limiter: opts.RateLimiter}
+	}
+
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+	}, nil
+}
+
+// getProxy returns the proxy function of the transport. The proxy environment variables are used for the settings
+// that are not set in the options.
+func getProxy(opts Options) (func(*http.Request) (*url.URL, error), error) {
+	if opts.HTTPSProxy == "" && opts.NoProxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	config := httpproxy.FromEnvironment()
+	if opts.HTTPSProxy != "" {
+		if _, err := url.Parse(opts.HTTPSProxy); err != nil {
+			return nil, fmt.Errorf("invalid https proxy %q: %w", opts.HTTPSProxy, err)
+		}
+		config.HTTPSProxy = opts.HTTPSProxy
+		config.HTTPProxy = opts.HTTPSProxy
+	}
+	if opts.NoProxy != "" {
+		config.NoProxy = opts.NoProxy
+	}
+
+	proxyFunc := config.ProxyFunc()
+
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
 }
 
 // Headers returns default HTTP headers for requests to Selectel API.
@@ -0,0 +1,238 @@
+package httpdefault_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/selectel/external-dns-selectel-webhook/pkg/httpdefault"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestNewClient(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0o600)
+	assert.NoError(t, err)
+
+	t.Run("Default options do not trust the server", func(t *testing.T) {
+		t.Parallel()
+
+		client, err := httpdefault.NewClient(httpdefault.DefaultOptions())
+		assert.NoError(t, err)
+
+		_, err = client.Get(server.URL)
+		assert.Error(t, err)
+	})
+
+	t.Run("CA file", func(t *testing.T) {
+		t.Parallel()
+
+		opts := httpdefault.DefaultOptions()
+		opts.CAFile = caFile
+
+		client, err := httpdefault.NewClient(opts)
+		assert.NoError(t, err)
+
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("Insecure skip verify", func(t *testing.T) {
+		t.Parallel()
+
+		opts := httpdefault.DefaultOptions()
+		opts.InsecureSkipVerify = true
+
+		client, err := httpdefault.NewClient(opts)
+		assert.NoError(t, err)
+
+		resp, err := client.Get(server.URL)
+		assert.NoError(t, err)
+		assert.NoError(t, resp.Body.Close())
+	})
+
+	t.Run("CA file without certificates", func(t *testing.T) {
+		t.Parallel()
+
+		emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+		assert.NoError(t, os.WriteFile(emptyFile, []byte("no certificates"), 0o600))
+
+		opts := httpdefault.DefaultOptions()
+		opts.CAFile = emptyFile
+
+		_, err := httpdefault.NewClient(opts)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing CA file", func(t *testing.T) {
+		t.Parallel()
+
+		opts := httpdefault.DefaultOptions()
+		opts.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+
+		_, err := httpdefault.NewClient(opts)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewClientProxy(t *testing.T) {
+	t.Parallel()
+
+	var proxied atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(proxy.Close)
+
+	opts := httpdefault.DefaultOptions()
+	opts.HTTPSProxy = proxy.URL
+
+	client, err := httpdefault.NewClient(opts)
+	assert.NoError(t, err)
+
+	resp, err := client.Get("http://api.selectel.invalid/domains/v2/zones")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, int64(1), proxied.Load())
+
+	opts.NoProxy = "direct.selectel.invalid"
+	client, err = httpdefault.NewClient(opts)
+	assert.NoError(t, err)
+
+	_, err = client.Get("http://direct.selectel.invalid/domains/v2/zones")
+	assert.Error(t, err)
+	assert.Equal(t, int64(1), proxied.Load())
+
+	opts.HTTPSProxy = "://invalid"
+	_, err = httpdefault.NewClient(opts)
+	assert.Error(t, err)
+}
+
+func TestNewClientRateLimit(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	opts := httpdefault.DefaultOptions()
+	opts.RateLimiter = limiter
+
+	client, err := httpdefault.NewClient(opts)
+	assert.NoError(t, err)
+
+	get := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		assert.NoError(t, err)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	}
+
+	assert.NoError(t, get())
+	// the next token is available in an hour
+	assert.Error(t, get())
+
+	limiter.SetLimit(rate.Inf)
+	assert.NoError(t, get())
+}
+
+func TestNewClientCAReload(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	// the CA file contains another certificate first
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, getSelfSignedCertificate(t), 0o600))
+
+	var reloads atomic.Int64
+	opts := httpdefault.DefaultOptions()
+	opts.CAFile = caFile
+	opts.CAReloadInterval = time.Nanosecond
+	opts.OnCAReload = func(err error) {
+		assert.NoError(t, err)
+		reloads.Add(1)
+	}
+
+	client, err := httpdefault.NewClient(opts)
+	assert.NoError(t, err)
+
+	_, err = client.Get(server.URL)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	}), 0o600))
+	// make sure the modification is noticed on file systems with a coarse timestamp resolution
+	modTime := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(caFile, modTime, modTime))
+
+	resp, err := client.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.NoError(t, resp.Body.Close())
+	assert.Equal(t, int64(1), reloads.Load())
+}
+
+// getSelfSignedCertificate returns a PEM encoded self-signed certificate.
+func getSelfSignedCertificate(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -0,0 +1,22 @@
+package httpdefault
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimitTransport waits for the limiter before every request. The wait is canceled with the context of the
+// request.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.next.RoundTrip(req)
+}
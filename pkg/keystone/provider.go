@@ -1,7 +1,9 @@
 package keystone
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -14,12 +16,16 @@ var (
 	errAuthorizationFailedFmt = "authorization failed: %w"
 )
 
-func defaultOSClient(endpoint string) (*gophercloud.ProviderClient, error) {
+func defaultOSClient(endpoint string, httpClient *http.Client) (*gophercloud.ProviderClient, error) {
 	client, err := openstack.NewClient(endpoint)
-	client.HTTPClient = httpdefault.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	client.HTTPClient = *httpClient
 	client.UserAgent.Prepend(httpdefault.UserAgent)
 
-	return client, err
+	return client, nil
 }
 
 type Credentials struct {
@@ -39,11 +45,13 @@ type Provider struct {
 	logger *zap.Logger
 	// credentials contains data to access openstack identity API.
 	credentials Credentials
+	// httpClient is used for the requests to the identity API.
+	httpClient *http.Client
 }
 
 // GetToken returns keystone token that may be used to authorize requests to Selectel API in the project scope.
-// It generates new token for each call.
-func (p Provider) GetToken() (string, error) {
+// It generates new token for each call. The request to the identity API is canceled with the given context.
+func (p Provider) GetToken(ctx context.Context) (string, error) {
 	p.logger.Info(
 		"getting keystone token",
 		zap.String("identity_endpoint", p.credentials.IdentityEndpoint),
@@ -63,12 +71,13 @@ func (p Provider) GetToken() (string, error) {
 	}
 
 	p.logger.Debug("connecting to identity endpoint")
-	client, err := defaultOSClient(p.credentials.IdentityEndpoint)
+	client, err := defaultOSClient(p.credentials.IdentityEndpoint, p.httpClient)
 	if err != nil {
 		p.logger.Error("error during creating default openstack client", zap.Error(err))
 
 		return "", fmt.Errorf(errFailedCreateClientFmt, err)
 	}
+	client.Context = ctx
 
 	err = openstack.Authenticate(client, opts)
 	if err != nil {
@@ -78,9 +87,17 @@ func (p Provider) GetToken() (string, error) {
 	return client.Token(), nil
 }
 
-func NewProvider(logger *zap.Logger, credentials Credentials) *Provider {
+// NewProvider returns a keystone provider sending its requests with the given HTTP client. The default client of
+// httpdefault is used if it is nil.
+func NewProvider(logger *zap.Logger, credentials Credentials, httpClient *http.Client) *Provider {
+	if httpClient == nil {
+		defaultClient := httpdefault.Client()
+		httpClient = &defaultClient
+	}
+
 	return &Provider{
 		logger:      logger,
 		credentials: credentials,
+		httpClient:  httpClient,
 	}
 }
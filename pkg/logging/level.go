@@ -0,0 +1,247 @@
+package logging
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ComponentKey is the field key of the component of a logger. The level of a logger created with
+// logger.With(zap.String(ComponentKey, name)) can be changed separately by Levels.
+const ComponentKey = "component"
+
+// Levels changes the level of a logger created by New and of its components at runtime. Changes can be reverted
+// automatically to the configured level.
+type Levels struct {
+	initial zapcore.Level
+	global  zap.AtomicLevel
+
+	mu         sync.Mutex
+	components map[string]*componentLevel
+	// reverts are the pending reverts by component, the global level has the empty component
+	reverts map[string]*revert
+}
+
+// ComponentLevel is the current level of a component.
+type ComponentLevel struct {
+	// Component is empty for the global level.
+	Component string
+	Level     zapcore.Level
+	// RevertAt is zero if the level is not reverted automatically.
+	RevertAt time.Time
+}
+
+// componentLevel overrides the global level for a component, nil follows the global level.
+type componentLevel struct {
+	override atomic.Pointer[zapcore.Level]
+}
+
+type revert struct {
+	timer *time.Timer
+	at    time.Time
+}
+
+func newLevels(level zapcore.Level) *Levels {
+	return &Levels{
+		initial:    level,
+		global:     zap.NewAtomicLevelAt(level),
+		components: make(map[string]*componentLevel),
+		reverts:    make(map[string]*revert),
+	}
+}
+
+// Set changes the level of the component or the global level if the component is empty. The change is reverted
+// after revertAfter, 0 keeps it until the next change.
+func (l *Levels) Set(component string, level zapcore.Level, revertAfter time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var c *componentLevel
+	if component != "" {
+		var ok bool
+		if c, ok = l.components[component]; !ok {
+			return fmt.Errorf(
+				"unknown log component %q, possible values are: %s", component, strings.Join(l.componentNames(), ", "),
+			)
+		}
+	}
+
+	l.stopRevert(component)
+
+	if c == nil {
+		l.global.SetLevel(level)
+	} else {
+		c.override.Store(&level)
+	}
+
+	if revertAfter > 0 {
+		r := &revert{at: time.Now().Add(revertAfter)}
+		r.timer = time.AfterFunc(revertAfter, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+
+			// a later change replaced this revert
+			if l.reverts[component] == r {
+				delete(l.reverts, component)
+				l.reset(component)
+			}
+		})
+		l.reverts[component] = r
+	}
+
+	return nil
+}
+
+// Reset reverts the level of the component to the global level or the global level to the configured one if the
+// component is empty.
+func (l *Levels) Reset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stopRevert(component)
+	l.reset(component)
+}
+
+// Configure replaces the configured level. The global level is only changed if it was not changed at runtime,
+// otherwise it is reverted to the new level.
+func (l *Levels) Configure(level zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.global.Level() == l.initial {
+		l.global.SetLevel(level)
+	}
+	l.initial = level
+}
+
+// Toggle switches the global level between debug and the configured level. A switch to debug is reverted after
+// revertAfter. It returns the new global level.
+func (l *Levels) Toggle(revertAfter time.Duration) zapcore.Level {
+	if l.global.Level() != zapcore.DebugLevel {
+		// the global level always exists
+		_ = l.Set("", zapcore.DebugLevel, revertAfter)
+	} else {
+		l.Reset("")
+	}
+
+	return l.global.Level()
+}
+
+// Snapshot returns the global level first and the levels of the components sorted by name.
+func (l *Levels) Snapshot() []ComponentLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	global := l.global.Level()
+	levels := []ComponentLevel{{Level: global, RevertAt: l.revertAt("")}}
+	for _, name := range l.componentNames() {
+		level := global
+		if override := l.components[name].override.Load(); override != nil {
+			level = *override
+		}
+		levels = append(levels, ComponentLevel{Component: name, Level: level, RevertAt: l.revertAt(name)})
+	}
+
+	return levels
+}
+
+func (l *Levels) enabled(component *componentLevel, level zapcore.Level) bool {
+	if component != nil {
+		if override := component.override.Load(); override != nil {
+			return override.Enabled(level)
+		}
+	}
+
+	return l.global.Enabled(level)
+}
+
+// component returns the level of the component and registers it on first use.
+func (l *Levels) component(name string) *componentLevel {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.components[name]
+	if !ok {
+		c = &componentLevel{}
+		l.components[name] = c
+	}
+
+	return c
+}
+
+func (l *Levels) reset(component string) {
+	if component == "" {
+		l.global.SetLevel(l.initial)
+
+		return
+	}
+
+	l.components[component].override.Store(nil)
+}
+
+func (l *Levels) stopRevert(component string) {
+	if r, ok := l.reverts[component]; ok {
+		r.timer.Stop()
+		delete(l.reverts, component)
+	}
+}
+
+func (l *Levels) revertAt(component string) time.Time {
+	if r, ok := l.reverts[component]; ok {
+		return r.at
+	}
+
+	return time.Time{}
+}
+
+func (l *Levels) componentNames() []string {
+	names := make([]string, 0, len(l.components))
+	for name := range l.components {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// levelCore enables the entries of a core by the levels of the component of the logger. Loggers without a component
+// follow the global level.
+type levelCore struct {
+	zapcore.Core
+	levels    *Levels
+	component *componentLevel
+}
+
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.levels.enabled(c.component, level)
+}
+
+// Level is used by zap to report the minimum enabled level of the logger.
+func (c *levelCore) Level() zapcore.Level {
+	return zapcore.LevelOf(zap.LevelEnablerFunc(c.Enabled))
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	component := c.component
+	for _, field := range fields {
+		if field.Key == ComponentKey && field.Type == zapcore.StringType {
+			component = c.levels.component(field.String)
+		}
+	}
+
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels, component: component}
+}
+
+func (c *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return c.Core.Check(entry, checked)
+	}
+
+	return checked
+}
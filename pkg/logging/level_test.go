@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevels(t *testing.T) {
+	t.Parallel()
+
+	output := filepath.Join(t.TempDir(), "log")
+
+	logger, levels, err := New(Config{Level: "info", Format: FormatLogfmt, OutputPath: output})
+	assert.NoError(t, err)
+
+	apiLogger := logger.With(zap.String(ComponentKey, "api"))
+	providerLogger := logger.With(zap.String(ComponentKey, "selprovider"))
+
+	assert.NoError(t, levels.Set("api", zapcore.DebugLevel, 0))
+	apiLogger.Debug("api debug")
+	providerLogger.Debug("selprovider debug")
+	logger.Debug("global debug")
+
+	assert.EqualError(t, levels.Set("keystone", zapcore.DebugLevel, 0),
+		`unknown log component "keystone", possible values are: api, selprovider`)
+
+	assert.NoError(t, levels.Set("", zapcore.ErrorLevel, 0))
+	providerLogger.Info("selprovider info")
+	apiLogger.Info("api info")
+
+	assert.Equal(t, []ComponentLevel{
+		{Level: zapcore.ErrorLevel},
+		{Component: "api", Level: zapcore.DebugLevel},
+		{Component: "selprovider", Level: zapcore.ErrorLevel},
+	}, levels.Snapshot())
+
+	levels.Reset("api")
+	levels.Reset("")
+	apiLogger.Debug("api debug after reset")
+	providerLogger.Info("selprovider info after reset")
+
+	assert.Equal(t, []string{"api debug", "api info", "selprovider info after reset"}, readMessages(t, output))
+}
+
+func TestLevelsRevert(t *testing.T) {
+	t.Parallel()
+
+	_, levels, err := New(Config{Level: "warn", Format: FormatJSON, OutputPath: "stdout"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, levels.Set("", zapcore.DebugLevel, time.Hour))
+	snapshot := levels.Snapshot()
+	assert.Equal(t, zapcore.DebugLevel, snapshot[0].Level)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), snapshot[0].RevertAt, time.Minute)
+
+	// a later change replaces the pending revert
+	assert.NoError(t, levels.Set("", zapcore.InfoLevel, 10*time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return levels.Snapshot()[0] == ComponentLevel{Level: zapcore.WarnLevel}
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Equal(t, zapcore.DebugLevel, levels.Toggle(time.Hour))
+	assert.Equal(t, zapcore.WarnLevel, levels.Toggle(time.Hour))
+	assert.Equal(t, ComponentLevel{Level: zapcore.WarnLevel}, levels.Snapshot()[0])
+
+	levels.Configure(zapcore.InfoLevel)
+	assert.Equal(t, zapcore.InfoLevel, levels.Snapshot()[0].Level)
+
+	// a level changed at runtime is kept until it is reverted to the new configured level
+	assert.NoError(t, levels.Set("", zapcore.DebugLevel, 0))
+	levels.Configure(zapcore.ErrorLevel)
+	assert.Equal(t, zapcore.DebugLevel, levels.Snapshot()[0].Level)
+	levels.Reset("")
+	assert.Equal(t, zapcore.ErrorLevel, levels.Snapshot()[0].Level)
+}
+
+var logfmtMessage = regexp.MustCompile(`msg="([^"]*)"`)
+
+// readMessages returns the messages of the logfmt lines written to path.
+func readMessages(t *testing.T, path string) []string {
+	t.Helper()
+
+	var messages []string
+	for _, line := range readLines(t, path) {
+		if match := logfmtMessage.FindStringSubmatch(line); match != nil {
+			messages = append(messages, match[1])
+		}
+	}
+
+	return messages
+}
@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder writes entries as logfmt key=value pairs. The entries are encoded by a JSON encoder first, so all
+// field types of zap are supported. Nested objects and arrays are written as quoted JSON.
+type logfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	config.LineEnding = zapcore.DefaultLineEnding
+
+	return &logfmtEncoder{Encoder: zapcore.NewJSONEncoder(config)}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := e.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	line := logfmtPool.Get()
+	if err := writeLogfmt(line, encoded.Bytes()); err != nil {
+		line.Free()
+
+		return nil, err
+	}
+	line.AppendString(zapcore.DefaultLineEnding)
+
+	return line, nil
+}
+
+// writeLogfmt converts a JSON object to logfmt keeping the order of the keys.
+func writeLogfmt(line *buffer.Buffer, object []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(object))
+	decoder.UseNumber()
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return fmt.Errorf("logfmt encoder expects a JSON object: %w", err)
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := token.(string)
+		if !ok {
+			return errors.New("logfmt encoder expects string keys")
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+
+		if line.Len() > 0 {
+			line.AppendByte(' ')
+		}
+		line.AppendString(key)
+		line.AppendByte('=')
+		writeLogfmtValue(line, value)
+	}
+
+	if _, err := decoder.Token(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+func writeLogfmtValue(line *buffer.Buffer, value json.RawMessage) {
+	text := string(value)
+
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		text = s
+	}
+
+	if text == "" || strings.ContainsAny(text, " =\"\t\r\n\\") {
+		var quoted bytes.Buffer
+		encoder := json.NewEncoder(&quoted)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(text)
+		line.AppendString(strings.TrimSuffix(quoted.String(), "\n"))
+
+		return
+	}
+
+	line.AppendString(text)
+}
@@ -0,0 +1,147 @@
+package logging
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// FormatJSON writes every entry as a JSON object.
+	FormatJSON = "json"
+	// FormatConsole writes every entry in the human-readable format of zap.
+	FormatConsole = "console"
+	// FormatLogfmt writes every entry as key=value pairs.
+	FormatLogfmt = "logfmt"
+)
+
+// LevelNames are the supported log levels.
+var LevelNames = []string{"debug", "info", "warn", "error"}
+
+// Formats are the supported log formats.
+var Formats = []string{FormatJSON, FormatConsole, FormatLogfmt}
+
+// Config is used to configure the logger created by New.
+type Config struct {
+	// Level is one of LevelNames.
+	Level string
+	// Format is one of Formats.
+	Format string
+	// OutputPath is a file path, stdout or stderr.
+	OutputPath string
+	// SamplingInitial is the number of entries with the same message and level logged per second before the
+	// sampling starts. Only entries below the warn level are sampled. 0 disables the sampling.
+	SamplingInitial int
+	// SamplingThereafter is the sampling rate of entries after SamplingInitial, every n-th entry is logged.
+	SamplingThereafter int
+	// Secrets are redacted from all messages and fields in addition to the common secret patterns.
+	Secrets []string
+}
+
+// ParseLevel returns the zap level of one of LevelNames.
+func ParseLevel(level string) (zapcore.Level, error) {
+	if !slices.Contains(LevelNames, level) {
+		return zapcore.InfoLevel, fmt.Errorf(
+			"unknown log level %q, possible values are: %s", level, strings.Join(LevelNames, ", "),
+		)
+	}
+
+	return zapcore.ParseLevel(level)
+}
+
+// New returns a logger configured by the given config and the levels to change its level at runtime. Errors and
+// secrets in messages and fields are redacted.
+func New(config Config) (*zap.Logger, *Levels, error) {
+	level, err := ParseLevel(config.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encoder, err := newEncoder(config.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	encoder = newRedactingEncoder(encoder, newRedactor(config.Secrets))
+
+	sink, _, err := zap.Open(config.OutputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errorSink, _, err := zap.Open("stderr")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the levels are checked by the levelCore, so the inner cores enable every level
+	var core zapcore.Core = zapcore.NewCore(encoder, sink, zapcore.DebugLevel)
+	if config.SamplingInitial > 0 {
+		core = newSampledCore(core, zapcore.DebugLevel, config.SamplingInitial, config.SamplingThereafter)
+	}
+
+	levels := newLevels(level)
+	core = &levelCore{Core: core, levels: levels}
+
+	return zap.New(core, zap.ErrorOutput(errorSink)), levels, nil
+}
+
+func newEncoder(format string) (zapcore.Encoder, error) {
+	encoderConfig := zap.NewProductionEncoderConfig()
+
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case FormatConsole:
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+		return zapcore.NewConsoleEncoder(encoderConfig), nil
+	case FormatLogfmt:
+		encoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
+
+		return newLogfmtEncoder(encoderConfig), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q, possible values are: %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// newSampledCore samples the entries below the warn level of the core, so noisy per-record lines can not flood the
+// log while warnings and errors are always written.
+func newSampledCore(core zapcore.Core, level zapcore.LevelEnabler, initial, thereafter int) zapcore.Core {
+	below := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l < zapcore.WarnLevel && level.Enabled(l)
+	})
+	above := zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= zapcore.WarnLevel && level.Enabled(l)
+	})
+
+	return zapcore.NewTee(
+		zapcore.NewSamplerWithOptions(&levelFilterCore{Core: core, level: below}, time.Second, initial, thereafter),
+		&levelFilterCore{Core: core, level: above},
+	)
+}
+
+// levelFilterCore restricts the levels of a core.
+type levelFilterCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+}
+
+func (c *levelFilterCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+func (c *levelFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelFilterCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c *levelFilterCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces secrets in log entries.
+const Redacted = "REDACTED"
+
+// secretPatterns match the common places of secrets in messages, e.g. error messages containing URLs or requests.
+// The first and second group of every pattern are kept.
+var secretPatterns = []*regexp.Regexp{
+	// password of the user info in URLs
+	regexp.MustCompile(`(://[^:/@\s]+:)[^@/\s]+(@)`),
+	// query parameters of URLs
+	regexp.MustCompile(`(?i)([?&][^=&\s]*(?:token|password|secret|key|signature)[^=&\s]*=)[^&\s"']+`),
+	// Keystone token headers
+	regexp.MustCompile(`(?i)(x-(?:auth|subject)-token["']?\s*[:=]\s*\[?["']?)[^\s"',\]]+`),
+	// authorization headers
+	regexp.MustCompile(`(?i)((?:bearer|basic)\s+)[^\s"',]+`),
+	// JSON fields
+	regexp.MustCompile(`(?i)("(?:password|token|secret)"\s*:\s*")[^"]*`),
+}
+
+// redactor removes secrets from strings.
+type redactor struct {
+	secrets []string
+}
+
+func newRedactor(secrets []string) *redactor {
+	r := &redactor{}
+	for _, secret := range secrets {
+		if secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+
+	return r
+}
+
+func (r *redactor) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+Redacted+"${2}")
+	}
+
+	return s
+}
+
+// redactingEncoder redacts the messages and the string, stringer and error fields of the entries before they are
+// encoded. Fields added with zap.Logger.With are redacted by AddString.
+type redactingEncoder struct {
+	zapcore.Encoder
+	redactor *redactor
+}
+
+func newRedactingEncoder(encoder zapcore.Encoder, redactor *redactor) zapcore.Encoder {
+	return &redactingEncoder{Encoder: encoder, redactor: redactor}
+}
+
+func (e *redactingEncoder) Clone() zapcore.Encoder {
+	return &redactingEncoder{Encoder: e.Encoder.Clone(), redactor: e.redactor}
+}
+
+func (e *redactingEncoder) AddString(key, value string) {
+	e.Encoder.AddString(key, e.redactor.redact(value))
+}
+
+func (e *redactingEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	entry.Message = e.redactor.redact(entry.Message)
+
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = e.redactField(field)
+	}
+
+	return e.Encoder.EncodeEntry(entry, redacted)
+}
+
+func (e *redactingEncoder) redactField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = e.redactor.redact(field.String)
+	case zapcore.StringerType:
+		if value, ok := stringerValue(field.Interface); ok {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: e.redactor.redact(value)}
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: e.redactor.redact(err.Error())}
+		}
+	}
+
+	return field
+}
+
+// stringerValue returns the string of a stringer field. Like zap, it recovers from a panic of a nil receiver, the
+// field is left to zap then.
+func stringerValue(value any) (result string, ok bool) {
+	stringer, ok := value.(fmt.Stringer)
+	if !ok {
+		return "", false
+	}
+
+	defer func() {
+		if recover() != nil {
+			result, ok = "", false
+		}
+	}()
+
+	return stringer.String(), true
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./provider.go
+//
+// Generated by this command:
+//
+//	mockgen -destination=./mock/provider.go -source=./provider.go ProviderMetrics
+//
+
+// Package mock_metrics is a generated GoMock package.
+package mock_metrics
+
+import (
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProviderMetrics is a mock of ProviderMetrics interface.
+type MockProviderMetrics struct {
+	ctrl     *gomock.Controller
+	recorder *MockProviderMetricsMockRecorder
+	isgomock struct{}
+}
+
+// MockProviderMetricsMockRecorder is the mock recorder for MockProviderMetrics.
+type MockProviderMetricsMockRecorder struct {
+	mock *MockProviderMetrics
+}
+
+// NewMockProviderMetrics creates a new mock instance.
+func NewMockProviderMetrics(ctrl *gomock.Controller) *MockProviderMetrics {
+	mock := &MockProviderMetrics{ctrl: ctrl}
+	mock.recorder = &MockProviderMetricsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProviderMetrics) EXPECT() *MockProviderMetricsMockRecorder {
+	return m.recorder
+}
+
+// CollectBlockedChange mocks base method.
+func (m *MockProviderMetrics) CollectBlockedChange(action, reason string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CollectBlockedChange", action, reason)
+}
+
+// CollectBlockedChange indicates an expected call of CollectBlockedChange.
+func (mr *MockProviderMetricsMockRecorder) CollectBlockedChange(action, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectBlockedChange", reflect.TypeOf((*MockProviderMetrics)(nil).CollectBlockedChange), action, reason)
+}
+
+// SetMassDeletionBreakerOpen mocks base method.
+func (m *MockProviderMetrics) SetMassDeletionBreakerOpen(open bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMassDeletionBreakerOpen", open)
+}
+
+// SetMassDeletionBreakerOpen indicates an expected call of SetMassDeletionBreakerOpen.
+func (mr *MockProviderMetricsMockRecorder) SetMassDeletionBreakerOpen(open any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMassDeletionBreakerOpen", reflect.TypeOf((*MockProviderMetrics)(nil).SetMassDeletionBreakerOpen), open)
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ProviderMetrics is an interface that defines the methods that can be used to collect provider metrics.
+//
+//go:generate mockgen -destination=./mock/provider.go -source=./provider.go ProviderMetrics
+type ProviderMetrics interface {
+	// CollectBlockedChange increment the total changes refused by a safety guard with the given action and reason
+	CollectBlockedChange(action, reason string)
+	// SetMassDeletionBreakerOpen set whether the mass deletion breaker refuses the batches
+	SetMassDeletionBreakerOpen(open bool)
+}
+
+// providerMetrics is a struct that implements the ProviderMetrics interface.
+type providerMetrics struct {
+	blockedChanges          *prometheus.CounterVec
+	massDeletionBreakerOpen prometheus.Gauge
+}
+
+// CollectBlockedChange increment the total changes refused by a safety guard with the given action and reason.
+func (p *providerMetrics) CollectBlockedChange(action, reason string) {
+	p.blockedChanges.WithLabelValues(action, reason).Inc()
+}
+
+// SetMassDeletionBreakerOpen set whether the mass deletion breaker refuses the batches.
+func (p *providerMetrics) SetMassDeletionBreakerOpen(open bool) {
+	if open {
+		p.massDeletionBreakerOpen.Set(1)
+
+		return
+	}
+
+	p.massDeletionBreakerOpen.Set(0)
+}
+
+// NewProviderMetrics returns a new instance of providerMetrics.
+func NewProviderMetrics() ProviderMetrics {
+	return &providerMetrics{
+		blockedChanges: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "provider_blocked_changes_total",
+			Help: "The total number of changes refused by the provider safety guards",
+		}, []string{"action", "reason"}),
+		massDeletionBreakerOpen: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "provider_mass_deletion_breaker_open",
+			Help: "Whether the mass deletion breaker refuses the batches of changes (1) or not (0)",
+		}),
+	}
+}
@@ -0,0 +1,20 @@
+package requestid
+
+import "context"
+
+// Header is the HTTP header the request id of a webhook call is read from.
+const Header = "X-Request-Id"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries the given request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id carried by ctx or an empty string if there is none.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}